@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/log"
+)
+
+// Tool approval modes, which say when a tool call needs the user's approval
+const (
+	approveAlways = "always"
+	approveWrites = "writes"
+	approveNever  = "never"
+)
+
+// argumentsPreviewLength limits how much of the arguments the approval
+// prompt shows
+const argumentsPreviewLength = 2000
+
+// validApprovalMode reports whether mode is one of the approval modes
+func validApprovalMode(mode string) bool {
+	return mode == approveAlways || mode == approveWrites || mode == approveNever
+}
+
+// toolApprovals remembers the tools the user allowed for good, per
+// project. A project is the working directory mcphost runs in.
+type toolApprovals struct {
+	// path is the file the decisions of every project are kept in
+	path    string
+	project string
+
+	mu      sync.Mutex
+	loaded  bool
+	allowed map[string][]string
+}
+
+// defaultApprovalsPath returns ~/.mcphost/tool-approvals.json
+func defaultApprovalsPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting home directory: %w", err)
+	}
+	return filepath.Join(home, ".mcphost", "tool-approvals.json"), nil
+}
+
+// load reads the remembered decisions the first time they are needed. A
+// missing file means nothing was remembered yet.
+func (a *toolApprovals) load() {
+	if a.loaded {
+		return
+	}
+	a.loaded = true
+	a.allowed = make(map[string][]string)
+
+	data, err := os.ReadFile(a.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &a.allowed)
+	}
+	if err != nil {
+		log.Warn("Ignoring remembered tool approvals", "file", a.path, "error", err)
+		a.allowed = make(map[string][]string)
+	}
+}
+
+// isAllowed reports whether the user allowed the tool in this project
+func (a *toolApprovals) isAllowed(tool string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.load()
+	return slices.Contains(a.allowed[a.project], tool)
+}
+
+// allow remembers that the user allowed the tool in this project
+func (a *toolApprovals) allow(tool string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.load()
+	if slices.Contains(a.allowed[a.project], tool) {
+		return nil
+	}
+	a.allowed[a.project] = append(a.allowed[a.project], tool)
+
+	data, err := json.MarshalIndent(a.allowed, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(a.path), 0o700); err != nil {
+		return fmt.Errorf("error creating %s: %w", filepath.Dir(a.path), err)
+	}
+	if err := os.WriteFile(a.path, data, 0o600); err != nil {
+		return fmt.Errorf("error saving tool approvals: %w", err)
+	}
+	return nil
+}
+
+// initToolApproval picks the approval mode: the --tool-approval flag, then
+// the config. Without either, tool calls run right away as they always did.
+func (ms *MCPSession) initToolApproval(flag string) error {
+	mode := flag
+	if mode == "" && ms.Config.ToolApproval != nil {
+		mode = ms.Config.ToolApproval.Mode
+	}
+	if mode == "" {
+		mode = approveNever
+	}
+	if !validApprovalMode(mode) {
+		return fmt.Errorf("invalid tool approval mode %q, expected always, writes or never", mode)
+	}
+	ms.ToolApproval = mode
+
+	path, err := defaultApprovalsPath()
+	if err != nil {
+		return err
+	}
+	project, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("error getting working directory: %v", err)
+	}
+	ms.approvals = &toolApprovals{path: path, project: project}
+	return nil
+}
+
+// needsApproval reports whether calling the tool needs the user's approval
+func (ms *MCPSession) needsApproval(serverName, toolName string) bool {
+	switch ms.ToolApproval {
+	case approveNever, "":
+		return false
+	case approveWrites:
+		if tool, ok := ms.tools.find(serverName, toolName); ok {
+			if hint := tool.Annotations.ReadOnlyHint; hint != nil && *hint {
+				return false
+			}
+		}
+	}
+	return ms.approvals == nil || !ms.approvals.isAllowed(serverName+"__"+toolName)
+}
+
+// approveToolCall asks the user whether a tool call may run, if it needs
+// approval. It returns why the call was denied, or "" if it may run.
+func (ms *MCPSession) approveToolCall(serverName, toolName string, args map[string]interface{}) string {
+	if !ms.needsApproval(serverName, toolName) {
+		return ""
+	}
+	if !ms.InTerminal {
+		return "The tool call was denied: it needs the user's approval and mcphost is not interactive."
+	}
+
+	preview, err := json.MarshalIndent(args, "", "  ")
+	if err != nil {
+		preview = []byte(fmt.Sprint(args))
+	}
+
+	const (
+		allowOnce    = "once"
+		allowAlways  = "always"
+		deny         = "deny"
+		denyBecause  = "reason"
+		deniedByUser = "The user denied this tool call."
+	)
+	choice := allowOnce
+	var reason string
+	err = askUser(func() error {
+		return huh.NewForm(
+			huh.NewGroup(
+				huh.NewNote().
+					Title(fmt.Sprintf("%s wants to run %s", serverName, toolName)).
+					Description(truncateText(string(preview), argumentsPreviewLength)),
+				huh.NewSelect[string]().
+					Title("Allow this tool call?").
+					Options(
+						huh.NewOption("Allow once", allowOnce),
+						huh.NewOption(fmt.Sprintf("Always allow %s in this project", toolName), allowAlways),
+						huh.NewOption("Deny", deny),
+						huh.NewOption("Deny and tell the model why", denyBecause),
+					).
+					Value(&choice),
+			),
+			huh.NewGroup(
+				huh.NewInput().
+					Title("Why not?").
+					Value(&reason),
+			).WithHideFunc(func() bool { return choice != denyBecause }),
+		).WithWidth(getTerminalWidth()).
+			WithTheme(huh.ThemeCharm()).
+			Run()
+	})
+	if errors.Is(err, huh.ErrUserAborted) {
+		return deniedByUser
+	}
+	if err != nil {
+		return fmt.Sprintf("The tool call was denied: %v", err)
+	}
+
+	switch choice {
+	case allowAlways:
+		if err := ms.approvals.allow(serverName + "__" + toolName); err != nil {
+			log.Warn("Failed to remember tool approval", "tool", toolName, "error", err)
+		}
+		return ""
+	case allowOnce:
+		return ""
+	case denyBecause:
+		if reason != "" {
+			return deniedByUser + " Reason: " + reason
+		}
+	}
+	return deniedByUser
+}
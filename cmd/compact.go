@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcphost/pkg/history"
+	"github.com/mark3labs/mcphost/pkg/llm"
+)
+
+const compactionPrompt = `You are compacting the history of a conversation between a user and an AI assistant that can call tools.
+Write a concise summary of the transcript below so that the assistant can continue the conversation without it.
+Preserve the user's goals and instructions, decisions that were made, important facts, file names, identifiers,
+error messages and the results of tool calls that are still relevant. Leave out pleasantries and dead ends.
+Reply with the summary only.
+
+<transcript>
+%s
+</transcript>`
+
+const summaryPrefix = "Summary of the earlier conversation:\n\n"
+
+// compactKeepRatio is the share of the available context kept verbatim when compacting
+const compactKeepRatio = 0.25
+
+// needsCompaction reports whether the history has grown past the automatic
+// compaction threshold. Without a context window to measure against, it
+// never has.
+func (ms *MCPSession) needsCompaction(messages []history.HistoryMessage) bool {
+	available := ms.availableTokens()
+	if ms.CompactThreshold <= 0 || available <= 0 {
+		return false
+	}
+	used := history.EstimateTokens(messages) +
+		llm.EstimateTextTokens(ms.SystemPrompt) +
+		llm.EstimateToolTokens(ms.requestTools())
+	return float64(used) > float64(available)*ms.CompactThreshold
+}
+
+// CompactMessages replaces the older part of the conversation with a single
+// summary message written by the model. The most recent exchanges are kept
+// verbatim. If a transcript directory is configured, the full history is
+// written there first.
+func (ms *MCPSession) CompactMessages(
+	ctx context.Context,
+	messages *[]history.HistoryMessage,
+) error {
+	split := compactionSplit(*messages, int(float64(max(ms.availableTokens(), 0))*compactKeepRatio))
+	if split == 0 {
+		return fmt.Errorf("nothing to compact")
+	}
+
+	if ms.TranscriptDir != "" {
+		path, err := saveTranscript(ms.TranscriptDir, *messages)
+		if err != nil {
+			return fmt.Errorf("error saving transcript: %w", err)
+		}
+		log.Info("Transcript saved", "path", path)
+	}
+
+	provider := ms.Provider
+	if ms.CompactProvider != nil {
+		provider = ms.CompactProvider
+	}
+
+	request := &history.HistoryMessage{
+		Role: "user",
+		Content: []history.ContentBlock{{
+			Type: "text",
+			Text: fmt.Sprintf(compactionPrompt, renderTranscript((*messages)[:split])),
+		}},
+	}
+	resp, err := provider.CreateMessage(ctx, "", []llm.Message{request}, nil)
+	if err != nil {
+		return fmt.Errorf("error summarizing conversation: %w", err)
+	}
+
+	summary := strings.TrimSpace(resp.GetContent())
+	if summary == "" {
+		return fmt.Errorf("model returned an empty summary")
+	}
+
+	// The kept messages start with a user message, which carries the
+	// summary so that user and assistant turns still alternate
+	compacted := append([]history.HistoryMessage(nil), (*messages)[split:]...)
+	first := compacted[0]
+	first.Content = append([]history.ContentBlock{{
+		Type: "text",
+		Text: summaryPrefix + summary,
+	}}, first.Content...)
+	compacted[0] = first
+
+	log.Info("Conversation compacted",
+		"messages_before", len(*messages),
+		"messages_after", len(compacted),
+		"tokens_before", history.EstimateTokens(*messages),
+		"tokens_after", history.EstimateTokens(compacted))
+
+	*messages = compacted
+	return nil
+}
+
+// compactionSplit returns the index of the first message to keep verbatim.
+// Only whole exchanges are kept, the last one always, and older ones while
+// they fit in keepTokens. Zero means there is nothing to compact.
+func compactionSplit(messages []history.HistoryMessage, keepTokens int) int {
+	split := len(messages)
+	kept := 0
+	for i := len(messages) - 1; i > 0; i-- {
+		kept += messages[i].EstimateTokens()
+		if messages[i].Role != "user" {
+			continue
+		}
+		if split != len(messages) && kept > keepTokens {
+			break
+		}
+		split = i
+	}
+	if split == len(messages) {
+		return 0
+	}
+	return split
+}
+
+// renderTranscript turns messages into plain text for the summarization prompt
+func renderTranscript(messages []history.HistoryMessage) string {
+	var sb strings.Builder
+	for _, msg := range messages {
+		for _, block := range msg.Content {
+			switch block.Type {
+			case "text":
+				role := "User"
+				if msg.Role == "assistant" {
+					role = "Assistant"
+				}
+				fmt.Fprintf(&sb, "%s: %s\n\n", role, block.Text)
+			case "resource":
+				fmt.Fprintf(&sb, "User attached: %s\n\n", block.Text)
+			case "tool_use":
+				fmt.Fprintf(&sb, "Assistant called tool %s with %s\n\n", block.Name, string(block.Input))
+			case "tool_result":
+				fmt.Fprintf(&sb, "Tool result: %s\n\n", toolResultText(block))
+			}
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+func toolResultText(block history.ContentBlock) string {
+	if block.Text != "" {
+		return block.Text
+	}
+	var texts []string
+	switch v := block.Content.(type) {
+	case string:
+		texts = append(texts, v)
+	case []mcp.Content:
+		for _, c := range v {
+			if text, ok := c.(mcp.TextContent); ok {
+				texts = append(texts, text.Text)
+			}
+		}
+	case []history.ContentBlock:
+		for _, c := range v {
+			if c.Type == "text" {
+				texts = append(texts, c.Text)
+			}
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// saveTranscript writes the full history to a new timestamped JSON file in
+// dir. The random suffix keeps compactions within the same second apart.
+func saveTranscript(dir string, messages []history.HistoryMessage) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(messages, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	file, err := os.CreateTemp(dir, fmt.Sprintf("transcript-%s-*.json", time.Now().Format("20060102-150405")))
+	if err != nil {
+		return "", err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+func handleCompactCommand(
+	ctx context.Context,
+	ms *MCPSession,
+	messages *[]history.HistoryMessage,
+) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var err error
+	_ = runCancellable("Compacting conversation...", cancel, func() {
+		err = ms.CompactMessages(ctx, messages)
+	})
+	if err != nil {
+		fmt.Printf("\n%s\n", errorStyle.Render(fmt.Sprintf("Error compacting conversation: %v", err)))
+		return
+	}
+	fmt.Printf("\n%s\n\n", promptStyle.Render("Conversation compacted."))
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/mark3labs/mcphost/pkg/history"
+)
+
+func TestCompactionSplit(t *testing.T) {
+	// tokens sums the estimates of messages, so budgets follow the estimator
+	tokens := func(messages ...history.HistoryMessage) int {
+		total := 0
+		for _, msg := range messages {
+			total += msg.EstimateTokens()
+		}
+		return total
+	}
+
+	first := []history.HistoryMessage{
+		userMessage("one"), toolUseMessage("a"), toolResultMessage("a"), assistantMessage("answer one"),
+	}
+	second := []history.HistoryMessage{
+		userMessage("two"), toolUseMessage("b"), toolResultMessage("b"), assistantMessage("answer two"),
+	}
+	last := []history.HistoryMessage{
+		userMessage("three"), toolUseMessage("c"), toolResultMessage("c"),
+		toolUseMessage("d"), toolResultMessage("d"),
+	}
+	conversation := append(append(append([]history.HistoryMessage{}, first...), second...), last...)
+
+	tests := []struct {
+		name       string
+		messages   []history.HistoryMessage
+		keepTokens int
+		want       int
+	}{
+		{
+			name:       "single exchange leaves nothing to compact",
+			messages:   last,
+			keepTokens: 0,
+			want:       0,
+		},
+		{
+			name:       "last exchange is kept even when it alone exceeds the budget",
+			messages:   conversation,
+			keepTokens: 1,
+			want:       len(first) + len(second),
+		},
+		{
+			name:       "keeps older exchanges while they fit",
+			messages:   conversation,
+			keepTokens: tokens(conversation[len(first):]...),
+			want:       len(first),
+		},
+		{
+			// One token short of the second exchange must not cut between
+			// its tool call and result; the whole exchange is summarized
+			name:       "never splits a tool call from its result",
+			messages:   conversation,
+			keepTokens: tokens(conversation[len(first):]...) - 1,
+			want:       len(first) + len(second),
+		},
+		{
+			name:       "everything fits but the first exchange is still summarized",
+			messages:   conversation,
+			keepTokens: tokens(conversation...),
+			want:       len(first),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compactionSplit(tt.messages, tt.keepTokens)
+			if got != tt.want {
+				t.Fatalf("compactionSplit() = %d, want %d", got, tt.want)
+			}
+			if got > 0 && tt.messages[got].Role != "user" {
+				t.Errorf("split lands on a %s message, want a user message", tt.messages[got].Role)
+			}
+		})
+	}
+}
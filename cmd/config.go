@@ -0,0 +1,338 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Work with the config file",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config file without starting any servers",
+	Args:  cobra.NoArgs,
+	// Problems with the file are not usage errors
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := configPath()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading config file %s: %w", path, err)
+		}
+		if _, err := parseMCPConfig(data); err != nil {
+			return fmt.Errorf("%s is invalid:\n%w", path, err)
+		}
+		fmt.Printf("%s is valid\n", path)
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// configError is a problem with the value at a JSON path of the config file
+type configError struct {
+	path string
+	msg  string
+}
+
+func (e *configError) Error() string {
+	if e.path == "" {
+		return e.msg
+	}
+	return e.path + ": " + e.msg
+}
+
+// parseMCPConfig decodes the config file, rejecting unknown keys and values
+// of the wrong type. Every problem found is reported with its JSON path.
+func parseMCPConfig(data []byte) (*MCPConfig, error) {
+	if !json.Valid(data) {
+		var config MCPConfig
+		err := json.Unmarshal(data, &config)
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line, col := position(data, syntaxErr.Offset)
+			return nil, fmt.Errorf("line %d, column %d: %v", line, col, err)
+		}
+		return nil, err
+	}
+
+	if errs := checkJSON("", data, reflect.TypeOf(MCPConfig{})); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	var config MCPConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	if config.ToolApproval != nil && config.ToolApproval.Mode != "" && !validApprovalMode(config.ToolApproval.Mode) {
+		return nil, &configError{
+			path: "toolApproval.mode",
+			msg:  fmt.Sprintf("unknown mode %q, expected always, writes or never", config.ToolApproval.Mode),
+		}
+	}
+	return &config, nil
+}
+
+// position turns a byte offset into a line and column
+func position(data []byte, offset int64) (int, int) {
+	line, col := 1, 1
+	for _, b := range data[:min(int(offset), len(data))] {
+		if b == '\n' {
+			line, col = line+1, 1
+		} else {
+			col++
+		}
+	}
+	return line, col
+}
+
+// serverConfigType returns the transport of a server entry. Without a type
+// field, entries with a url are SSE servers and all others are stdio.
+func serverConfigType(data []byte) (string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return "", &configError{msg: "must be an object"}
+	}
+
+	if raw, ok := fields["type"]; ok {
+		var serverType string
+		if err := json.Unmarshal(raw, &serverType); err != nil {
+			return "", &configError{path: "type", msg: "must be a string"}
+		}
+		switch serverType {
+		case transportStdio, transportSSE, transportHTTP:
+			return serverType, nil
+		}
+		return "", &configError{
+			path: "type",
+			msg:  fmt.Sprintf("unknown transport %q, expected stdio, sse or http", serverType),
+		}
+	}
+	if _, ok := fields["url"]; ok {
+		return transportSSE, nil
+	}
+	return transportStdio, nil
+}
+
+// serverConfigTypes maps transports to their config types
+var serverConfigTypes = map[string]reflect.Type{
+	transportStdio: reflect.TypeOf(STDIOServerConfig{}),
+	transportSSE:   reflect.TypeOf(SSEServerConfig{}),
+	transportHTTP:  reflect.TypeOf(StreamableHTTPServerConfig{}),
+}
+
+var (
+	serverConfigWrapperType = reflect.TypeOf(ServerConfigWrapper{})
+	unmarshalerType         = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// checkJSON compares data with the JSON layout of t and returns every
+// unknown key and badly typed value, with its path
+func checkJSON(path string, data json.RawMessage, t reflect.Type) []error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if string(data) == "null" {
+		return nil
+	}
+
+	if t == serverConfigWrapperType {
+		return checkServerConfig(path, data)
+	}
+
+	if reflect.PointerTo(t).Implements(unmarshalerType) ||
+		t.Kind() != reflect.Struct && t.Kind() != reflect.Map && t.Kind() != reflect.Slice {
+		if err := json.Unmarshal(data, reflect.New(t).Interface()); err != nil {
+			return []error{&configError{path: path, msg: describeJSONError(err)}}
+		}
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Map:
+		var entries map[string]json.RawMessage
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return []error{&configError{path: path, msg: "must be an object"}}
+		}
+		var errs []error
+		for _, key := range sortedKeys(entries) {
+			errs = append(errs, checkJSON(joinPath(path, key), entries[key], t.Elem())...)
+		}
+		return errs
+
+	case reflect.Slice:
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return []error{&configError{path: path, msg: "must be an array"}}
+		}
+		var errs []error
+		for i, item := range items {
+			errs = append(errs, checkJSON(fmt.Sprintf("%s[%d]", path, i), item, t.Elem())...)
+		}
+		return errs
+	}
+
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return []error{&configError{path: path, msg: "must be an object"}}
+	}
+	fields := jsonFields(t)
+	var errs []error
+	for _, key := range sortedKeys(entries) {
+		fieldType, ok := fields[key]
+		if !ok {
+			errs = append(errs, &configError{path: joinPath(path, key), msg: unknownKeyMessage(key, fields)})
+			continue
+		}
+		errs = append(errs, checkJSON(joinPath(path, key), entries[key], fieldType)...)
+	}
+	return errs
+}
+
+// checkServerConfig checks a server entry against the config type of its
+// transport, and that the fields the transport needs are set
+func checkServerConfig(path string, data json.RawMessage) []error {
+	serverType, err := serverConfigType(data)
+	if err != nil {
+		configErr := err.(*configError)
+		return []error{&configError{path: joinPath(path, configErr.path), msg: configErr.msg}}
+	}
+
+	t := serverConfigTypes[serverType]
+	errs := checkJSON(path, data, t)
+
+	config := reflect.New(t)
+	if err := json.Unmarshal(data, config.Interface()); err != nil {
+		// Already reported by checkJSON
+		return errs
+	}
+
+	options := config.Elem().Interface().(ServerConfig).GetOptions()
+	for _, field := range []struct {
+		key      string
+		patterns []string
+	}{
+		{"allowedTools", options.AllowedTools},
+		{"disabledTools", options.DisabledTools},
+	} {
+		for i, pattern := range field.patterns {
+			if err := checkToolPattern(pattern); err != nil {
+				errs = append(errs, &configError{
+					path: fmt.Sprintf("%s[%d]", joinPath(path, field.key), i),
+					msg:  err.Error(),
+				})
+			}
+		}
+	}
+
+	switch c := config.Elem().Interface().(type) {
+	case STDIOServerConfig:
+		if c.Command == "" {
+			errs = append(errs, &configError{path: joinPath(path, "command"), msg: "is required for stdio servers"})
+		}
+	case SSEServerConfig:
+		errs = append(errs, checkRemoteServer(path, c.Url, c.Headers)...)
+	case StreamableHTTPServerConfig:
+		errs = append(errs, checkRemoteServer(path, c.Url, c.Headers)...)
+	}
+	return errs
+}
+
+func checkRemoteServer(path, serverURL string, headers []string) []error {
+	var errs []error
+	if serverURL == "" {
+		errs = append(errs, &configError{path: joinPath(path, "url"), msg: "is required for sse and http servers"})
+	} else if u, err := url.Parse(serverURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, &configError{path: joinPath(path, "url"), msg: fmt.Sprintf("%q is not an http or https URL", serverURL)})
+	}
+	for i, header := range headers {
+		if name, _, ok := strings.Cut(header, ":"); !ok || strings.TrimSpace(name) == "" {
+			errs = append(errs, &configError{
+				path: fmt.Sprintf("%s[%d]", joinPath(path, "headers"), i),
+				msg:  "expected \"Name: value\"",
+			})
+		}
+	}
+	return errs
+}
+
+// jsonFields returns the JSON keys of a struct and their types, including
+// those of embedded structs
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			for key, fieldType := range jsonFields(field.Type) {
+				fields[key] = fieldType
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+func unknownKeyMessage(key string, fields map[string]reflect.Type) string {
+	for name := range fields {
+		if strings.EqualFold(name, key) {
+			return fmt.Sprintf("unknown key, did you mean %q?", name)
+		}
+	}
+	return "unknown key"
+}
+
+func describeJSONError(err error) string {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return fmt.Sprintf("expected %s, got %s", typeErr.Type, typeErr.Value)
+	}
+	return err.Error()
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	if key == "" {
+		return path
+	}
+	return path + "." + key
+}
+
+func sortedKeys(entries map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
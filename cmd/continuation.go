@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/charmbracelet/log"
+	"github.com/mark3labs/mcphost/pkg/history"
+	"github.com/mark3labs/mcphost/pkg/llm"
+)
+
+const continuePrompt = "Your previous reply was cut off because it reached the output token limit. " +
+	"Continue exactly where it stopped, without repeating anything and without any preamble."
+
+// maxTokensCeiling caps the output token limit when retrying a tool call
+// that was cut off, for models whose limit is unknown or larger
+const maxTokensCeiling = 32768
+
+// completeTruncated deals with responses that stopped at the output token
+// limit. Cut off tool calls are retried with a larger limit, since their
+// arguments cannot be used. Cut off text is continued up to
+// MaxContinuations times and stitched together.
+func (ms *MCPSession) completeTruncated(
+	ctx context.Context,
+	prompt string,
+	llmMessages []llm.Message,
+	opts []llm.RequestOption,
+	message llm.Message,
+	callback func(
+		ctx context.Context,
+		text string,
+		role int,
+		action func(),
+	) error,
+) (llm.Message, error) {
+	message, err := ms.retryTruncatedToolCall(ctx, prompt, llmMessages, opts, message, callback)
+	if err != nil {
+		return nil, err
+	}
+	if !llm.IsTruncated(message) {
+		return message, nil
+	}
+
+	// The continuation is plain text, so it is neither forced into a tool
+	// call nor held to the output schema
+	continueOpts := append(append([]llm.RequestOption{}, opts...), func(o *llm.RequestOptions) {
+		o.ToolChoice = nil
+		o.OutputSchema = nil
+	})
+
+	text := message.GetContent()
+	for i := 0; llm.IsTruncated(message); i++ {
+		if i >= ms.MaxContinuations {
+			log.Warn("Response was cut off at the output token limit",
+				"continuations", ms.MaxContinuations)
+			break
+		}
+		log.Info("Response was cut off at the output token limit, continuing",
+			"continuation", i+1)
+
+		continued := make([]llm.Message, 0, len(llmMessages)+2)
+		continued = append(continued, llmMessages...)
+		continued = append(continued,
+			&history.HistoryMessage{
+				Role:    "assistant",
+				Content: []history.ContentBlock{{Type: "text", Text: text}},
+			},
+			&history.HistoryMessage{
+				Role:    "user",
+				Content: []history.ContentBlock{{Type: "text", Text: continuePrompt}},
+			},
+		)
+
+		callback(ctx, "", MODE_CREATE_MESSAGE, func() {
+			message, err = ms.Provider.CreateMessage(ctx, "", continued, ms.requestTools(), continueOpts...)
+		})
+		if err != nil {
+			return nil, err
+		}
+		// A continuation can end in a tool call that was cut off too
+		message, err = ms.retryTruncatedToolCall(ctx, "", continued, continueOpts, message, callback)
+		if err != nil {
+			return nil, err
+		}
+		text += message.GetContent()
+	}
+
+	return stitchedMessage(text, message.GetToolCalls()), nil
+}
+
+// retryTruncatedToolCall repeats a request whose response is a tool call
+// that was cut off at the output token limit, doubling the limit the request
+// used up to what the model allows. Other responses are returned as they are.
+func (ms *MCPSession) retryTruncatedToolCall(
+	ctx context.Context,
+	prompt string,
+	llmMessages []llm.Message,
+	opts []llm.RequestOption,
+	message llm.Message,
+	callback func(
+		ctx context.Context,
+		text string,
+		role int,
+		action func(),
+	) error,
+) (llm.Message, error) {
+	var err error
+
+	caps := ms.Provider.Capabilities()
+	ceiling := maxTokensCeiling
+	if caps.MaxOutputTokens > 0 && caps.MaxOutputTokens < ceiling {
+		ceiling = caps.MaxOutputTokens
+	}
+
+	// The limit the truncated request ran into
+	maxTokens := llm.NewRequestOptions(opts...).MaxTokens
+	if maxTokens == 0 {
+		maxTokens = caps.DefaultOutputTokens
+	}
+	if maxTokens == 0 {
+		maxTokens = llm.DefaultMaxTokens
+	}
+	for llm.IsTruncated(message) && len(message.GetToolCalls()) > 0 {
+		toolName := message.GetToolCalls()[0].GetName()
+		// Retrying only helps if the limit can be raised
+		if maxTokens >= ceiling {
+			return nil, fmt.Errorf(
+				"the call to tool %s was cut off at the output token limit of %d tokens",
+				toolName,
+				maxTokens,
+			)
+		}
+		maxTokens = min(maxTokens*2, ceiling)
+		log.Warn("Tool call was cut off at the output token limit, retrying with a larger limit",
+			"tool", toolName,
+			"max_tokens", maxTokens)
+
+		retryOpts := append(append([]llm.RequestOption{}, opts...), llm.WithMaxTokens(maxTokens))
+		callback(ctx, "", MODE_CREATE_MESSAGE, func() {
+			message, err = ms.Provider.CreateMessage(ctx, prompt, llmMessages, ms.requestTools(), retryOpts...)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return message, nil
+}
+
+// stitchedMessage builds the assistant message for a continued reply
+func stitchedMessage(text string, toolCalls []llm.ToolCall) *history.HistoryMessage {
+	msg := &history.HistoryMessage{Role: "assistant"}
+	if text != "" {
+		msg.Content = append(msg.Content, history.ContentBlock{Type: "text", Text: text})
+	}
+	for _, call := range toolCalls {
+		input, _ := json.Marshal(call.GetArguments())
+		msg.Content = append(msg.Content, history.ContentBlock{
+			Type:  "tool_use",
+			ID:    call.GetID(),
+			Name:  call.GetName(),
+			Input: input,
+		})
+	}
+	return msg
+}
@@ -0,0 +1,462 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/log"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcphost/pkg/jsonschema"
+)
+
+// elicitationHandler answers a server's elicitation/create requests by
+// asking the user to fill in a form built from the requested schema
+type elicitationHandler struct {
+	ms     *MCPSession
+	server string
+}
+
+func (h *elicitationHandler) Elicit(ctx context.Context, request mcp.ElicitationRequest) (*mcp.ElicitationResult, error) {
+	params := request.Params
+	if !h.ms.InTerminal {
+		log.Info("Elicitation request declined: mcphost is not interactive", "server", h.server)
+		return elicitationResult(mcp.ElicitationResponseActionDecline, nil), nil
+	}
+
+	if params.Mode == mcp.ElicitationModeURL {
+		return h.elicitURL(params)
+	}
+
+	schema, err := elicitationSchema(params.RequestedSchema)
+	if err != nil {
+		return nil, err
+	}
+	return h.elicitForm(params.Message, schema)
+}
+
+func elicitationResult(action mcp.ElicitationResponseAction, content any) *mcp.ElicitationResult {
+	return &mcp.ElicitationResult{
+		ElicitationResponse: mcp.ElicitationResponse{Action: action, Content: content},
+	}
+}
+
+// elicitationSchema converts the requested schema into the decoded JSON
+// form the validator expects
+func elicitationSchema(requested any) (map[string]interface{}, error) {
+	data, err := json.Marshal(requested)
+	if err != nil {
+		return nil, fmt.Errorf("invalid requested schema: %w", err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil || schema == nil {
+		return nil, fmt.Errorf("requested schema must be an object")
+	}
+	return schema, nil
+}
+
+// elicitURL shows the URL the server wants the user to open
+func (h *elicitationHandler) elicitURL(params mcp.ElicitationParams) (*mcp.ElicitationResult, error) {
+	const (
+		open    = "open"
+		decline = "decline"
+	)
+	choice := decline
+	err := askUser(func() error {
+		return huh.NewForm(huh.NewGroup(
+			huh.NewNote().
+				Title(fmt.Sprintf("%s asks you to open a URL", h.server)).
+				Description(params.Message+"\n\n"+params.URL),
+			huh.NewSelect[string]().
+				Options(
+					huh.NewOption("I will open it", open),
+					huh.NewOption("Decline", decline),
+				).
+				Value(&choice),
+		)).WithWidth(getTerminalWidth()).
+			WithTheme(huh.ThemeCharm()).
+			Run()
+	})
+	if errors.Is(err, huh.ErrUserAborted) {
+		return elicitationResult(mcp.ElicitationResponseActionCancel, nil), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if choice == open {
+		return elicitationResult(mcp.ElicitationResponseActionAccept, nil), nil
+	}
+	return elicitationResult(mcp.ElicitationResponseActionDecline, nil), nil
+}
+
+// elicitForm asks for the properties of schema and returns them once they
+// validate, or the user's decision not to answer
+func (h *elicitationHandler) elicitForm(message string, schema map[string]interface{}) (*mcp.ElicitationResult, error) {
+	const (
+		respond = "respond"
+		decline = "decline"
+	)
+	choice := respond
+	fields := elicitationFields(schema)
+
+	for {
+		formFields := make([]huh.Field, len(fields))
+		for i, field := range fields {
+			formFields[i] = field.input
+		}
+		groups := []*huh.Group{huh.NewGroup(
+			huh.NewNote().
+				Title(fmt.Sprintf("%s asks for input", h.server)).
+				Description(message),
+			huh.NewSelect[string]().
+				Options(
+					huh.NewOption("Respond", respond),
+					huh.NewOption("Decline", decline),
+				).
+				Value(&choice),
+		)}
+		if len(formFields) > 0 {
+			groups = append(groups, huh.NewGroup(formFields...).WithHideFunc(func() bool {
+				return choice != respond
+			}))
+		}
+
+		err := askUser(func() error {
+			return huh.NewForm(groups...).
+				WithWidth(getTerminalWidth()).
+				WithTheme(huh.ThemeCharm()).
+				Run()
+		})
+		if errors.Is(err, huh.ErrUserAborted) {
+			log.Info("Elicitation request cancelled", "server", h.server)
+			return elicitationResult(mcp.ElicitationResponseActionCancel, nil), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if choice == decline {
+			log.Info("Elicitation request declined", "server", h.server)
+			return elicitationResult(mcp.ElicitationResponseActionDecline, nil), nil
+		}
+
+		content := make(map[string]interface{})
+		for _, field := range fields {
+			if value, ok := field.value(); ok {
+				content[field.name] = value
+			}
+		}
+		// The fields check their own values, this catches the rest
+		if err := jsonschema.Validate(schema, content); err != nil {
+			fmt.Printf("\n%s\n\n", errorStyle.Render(fmt.Sprintf("Invalid input: %v", err)))
+			continue
+		}
+		return elicitationResult(mcp.ElicitationResponseActionAccept, content), nil
+	}
+}
+
+// elicitationField is a form field for one property of the requested
+// schema. value returns the converted value and whether one was given.
+type elicitationField struct {
+	name  string
+	input huh.Field
+	value func() (interface{}, bool)
+}
+
+// elicitationFields builds the form fields for the properties of schema,
+// required ones first
+func elicitationFields(schema map[string]interface{}) []elicitationField {
+	properties, _ := schema["properties"].(map[string]interface{})
+	required := make(map[string]bool)
+	if names, ok := schema["required"].([]interface{}); ok {
+		for _, name := range names {
+			if s, ok := name.(string); ok {
+				required[s] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if required[names[i]] != required[names[j]] {
+			return required[names[i]]
+		}
+		return names[i] < names[j]
+	})
+
+	fields := make([]elicitationField, 0, len(names))
+	for _, name := range names {
+		property, _ := properties[name].(map[string]interface{})
+		if property == nil {
+			property = map[string]interface{}{}
+		}
+		fields = append(fields, elicitationPropertyField(name, property, required[name]))
+	}
+	return fields
+}
+
+func elicitationPropertyField(name string, property map[string]interface{}, required bool) elicitationField {
+	title, _ := property["title"].(string)
+	if title == "" {
+		title = name
+	}
+	if required {
+		title += " *"
+	}
+	description, _ := property["description"].(string)
+	propertyType, _ := property["type"].(string)
+
+	switch {
+	case propertyType == "boolean" && !required:
+		// An optional answer is only sent if the user gives one
+		var value string
+		if d, ok := property["default"].(bool); ok {
+			value = strconv.FormatBool(d)
+		}
+		return elicitationField{
+			name: name,
+			input: huh.NewSelect[string]().
+				Title(title).
+				Description(description).
+				Options(
+					huh.NewOption("(none)", ""),
+					huh.NewOption("Yes", "true"),
+					huh.NewOption("No", "false"),
+				).
+				Value(&value),
+			value: func() (interface{}, bool) { return value == "true", value != "" },
+		}
+
+	case propertyType == "boolean":
+		value, _ := property["default"].(bool)
+		return elicitationField{
+			name: name,
+			input: huh.NewConfirm().
+				Title(title).
+				Description(description).
+				Affirmative("Yes").
+				Negative("No").
+				Value(&value),
+			value: func() (interface{}, bool) { return value, true },
+		}
+
+	case propertyType == "array" && len(enumOptions(itemsSchema(property))) == 0:
+		// Free-form items are typed as a comma-separated list
+		itemType, _ := itemsSchema(property)["type"].(string)
+		var text string
+		if defaults, ok := property["default"].([]interface{}); ok {
+			parts := make([]string, len(defaults))
+			for i, d := range defaults {
+				parts[i] = fmt.Sprint(d)
+			}
+			text = strings.Join(parts, ", ")
+		}
+		parse := func(s string) ([]interface{}, error) {
+			var values []interface{}
+			for _, part := range strings.Split(s, ",") {
+				part = strings.TrimSpace(part)
+				if part == "" {
+					continue
+				}
+				value, err := parseElicitationValue(itemType, part)
+				if err != nil {
+					return nil, err
+				}
+				values = append(values, value)
+			}
+			return values, nil
+		}
+		return elicitationField{
+			name: name,
+			input: huh.NewInput().
+				Title(title).
+				Description(strings.TrimSpace(description + " (comma-separated)")).
+				Value(&text).
+				Validate(func(s string) error {
+					values, err := parse(s)
+					if err != nil {
+						return err
+					}
+					if len(values) == 0 {
+						if required {
+							return fmt.Errorf("%s is required", name)
+						}
+						return nil
+					}
+					return jsonschema.Validate(property, values)
+				}),
+			value: func() (interface{}, bool) {
+				values, err := parse(text)
+				if err != nil || len(values) == 0 {
+					return []interface{}{}, required
+				}
+				return values, true
+			},
+		}
+
+	case propertyType == "array":
+		items := itemsSchema(property)
+		var selected []string
+		if defaults, ok := property["default"].([]interface{}); ok {
+			for _, d := range defaults {
+				if s, ok := d.(string); ok {
+					selected = append(selected, s)
+				}
+			}
+		}
+		convert := func(values []string) []interface{} {
+			converted := make([]interface{}, len(values))
+			for i, v := range values {
+				converted[i] = v
+			}
+			return converted
+		}
+		return elicitationField{
+			name: name,
+			input: huh.NewMultiSelect[string]().
+				Title(title).
+				Description(description).
+				Options(enumOptions(items)...).
+				Value(&selected).
+				Validate(func(values []string) error {
+					if required && len(values) == 0 {
+						return fmt.Errorf("%s is required", name)
+					}
+					return jsonschema.Validate(property, convert(values))
+				}),
+			value: func() (interface{}, bool) {
+				return convert(selected), len(selected) > 0 || required
+			},
+		}
+
+	case len(enumOptions(property)) > 0:
+		value, _ := property["default"].(string)
+		options := enumOptions(property)
+		if !required {
+			options = append([]huh.Option[string]{huh.NewOption("(none)", "")}, options...)
+		}
+		return elicitationField{
+			name: name,
+			input: huh.NewSelect[string]().
+				Title(title).
+				Description(description).
+				Options(options...).
+				Value(&value),
+			value: func() (interface{}, bool) { return value, value != "" },
+		}
+	}
+
+	var text string
+	if d, ok := property["default"]; ok && d != nil {
+		text = fmt.Sprint(d)
+	}
+	if format, ok := property["format"].(string); ok && format != "" {
+		description = strings.TrimSpace(description + " (" + format + ")")
+	}
+	return elicitationField{
+		name: name,
+		input: huh.NewInput().
+			Title(title).
+			Description(description).
+			Value(&text).
+			Validate(func(s string) error {
+				if s == "" {
+					if required {
+						return fmt.Errorf("%s is required", name)
+					}
+					return nil
+				}
+				value, err := parseElicitationValue(propertyType, s)
+				if err != nil {
+					return err
+				}
+				return jsonschema.Validate(property, value)
+			}),
+		value: func() (interface{}, bool) {
+			if text == "" {
+				return nil, false
+			}
+			value, err := parseElicitationValue(propertyType, text)
+			if err != nil {
+				return text, true
+			}
+			return value, true
+		},
+	}
+}
+
+// itemsSchema returns the schema of an array property's items
+func itemsSchema(property map[string]interface{}) map[string]interface{} {
+	items, _ := property["items"].(map[string]interface{})
+	return items
+}
+
+// parseElicitationValue converts text typed by the user to the property's
+// type. Numbers are float64 as encoding/json would decode them.
+func parseElicitationValue(propertyType, text string) (interface{}, error) {
+	switch propertyType {
+	case "integer":
+		n, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", text)
+		}
+		return float64(n), nil
+	case "number":
+		n, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", text)
+		}
+		return n, nil
+	}
+	return text, nil
+}
+
+// enumOptions returns the choices of a string property, given either as
+// enum with optional enumNames or as oneOf/anyOf entries with const and title
+func enumOptions(property map[string]interface{}) []huh.Option[string] {
+	var options []huh.Option[string]
+	if enum, ok := property["enum"].([]interface{}); ok {
+		names, _ := property["enumNames"].([]interface{})
+		for i, v := range enum {
+			value, ok := v.(string)
+			if !ok {
+				continue
+			}
+			label := value
+			if i < len(names) {
+				if name, ok := names[i].(string); ok && name != "" {
+					label = name
+				}
+			}
+			options = append(options, huh.NewOption(label, value))
+		}
+		return options
+	}
+
+	for _, key := range []string{"oneOf", "anyOf"} {
+		choices, ok := property[key].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, c := range choices {
+			choice, _ := c.(map[string]interface{})
+			value, ok := choice["const"].(string)
+			if !ok {
+				continue
+			}
+			label, _ := choice["title"].(string)
+			if label == "" {
+				label = value
+			}
+			options = append(options, huh.NewOption(label, value))
+		}
+	}
+	return options
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"time"
@@ -13,18 +14,23 @@ import (
 	"github.com/charmbracelet/lipgloss/list"
 	"github.com/charmbracelet/log"
 
+	"sort"
 	"strings"
+	"sync"
 
 	mcpclient "github.com/mark3labs/mcp-go/client"
 	"github.com/mark3labs/mcp-go/client/transport"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcphost/pkg/history"
+	"github.com/mark3labs/mcphost/pkg/httpclient"
 	"github.com/mark3labs/mcphost/pkg/llm"
+	"github.com/mark3labs/mcphost/pkg/llm/ollama"
 )
 
 const (
 	transportStdio = "stdio"
 	transportSSE   = "sse"
+	transportHTTP  = "http"
 )
 
 var (
@@ -67,13 +73,228 @@ var (
 
 type MCPConfig struct {
 	MCPServers map[string]ServerConfigWrapper `json:"mcpServers"`
+	Providers  *ProvidersConfig               `json:"providers,omitempty"`
+	// HTTP holds the defaults for every provider and SSE server connection
+	HTTP *httpclient.Config `json:"http,omitempty"`
+	// Sampling chooses the models that answer servers' sampling requests
+	Sampling *SamplingConfig `json:"sampling,omitempty"`
+	// Roots are directories offered to servers besides the working directory
+	Roots []string `json:"roots,omitempty"`
+	// ToolApproval chooses which tool calls the user approves first
+	ToolApproval *ToolApprovalConfig `json:"toolApproval,omitempty"`
+
+	// tracer records provider HTTP traffic when --trace-http is set
+	tracer *httpclient.Tracer
+}
+
+// ToolApprovalConfig chooses which tool calls the user approves first
+type ToolApprovalConfig struct {
+	// Mode is always, writes (tools not marked read-only) or never
+	Mode string `json:"mode,omitempty"`
+}
+
+// SamplingConfig chooses the models that answer sampling requests
+type SamplingConfig struct {
+	// Model answers requests whose model hints match none of Models. It
+	// defaults to the session's model.
+	Model string `json:"model,omitempty"`
+	// Models are provider:model names servers can pick through model hints
+	Models []string `json:"models,omitempty"`
+	// MaxTokens caps the tokens a server can ask for, zero leaves it to the
+	// server
+	MaxTokens int `json:"maxTokens,omitempty"`
+}
+
+// ProvidersConfig holds provider specific settings
+type ProvidersConfig struct {
+	Anthropic *ProviderConfig       `json:"anthropic,omitempty"`
+	OpenAI    *OpenAIProviderConfig `json:"openai,omitempty"`
+	Google    *ProviderConfig       `json:"google,omitempty"`
+	Ollama    *OllamaProviderConfig `json:"ollama,omitempty"`
+}
+
+// ProviderConfig holds the settings shared by all providers
+type ProviderConfig struct {
+	// HTTP overrides the global HTTP settings for this provider
+	HTTP *httpclient.Config `json:"http,omitempty"`
+	// Headers and QueryParams are added to every request
+	Headers     map[string]string `json:"headers,omitempty"`
+	QueryParams map[string]string `json:"queryParams,omitempty"`
+	// HeadersCommand prints "Name: value" lines with headers to add to each
+	// request, e.g. short-lived tokens. Its output is cached for
+	// HeadersCommandTTL if set.
+	HeadersCommand    []string            `json:"headersCommand,omitempty"`
+	HeadersCommandTTL httpclient.Duration `json:"headersCommandTtl,omitempty"`
+}
+
+type OpenAIProviderConfig struct {
+	ProviderConfig
+	Organization string `json:"organization,omitempty"`
+	Project      string `json:"project,omitempty"`
+}
+
+type OllamaProviderConfig struct {
+	ollama.Config
+	ProviderConfig
+}
+
+// ollamaConfig returns the Ollama settings, or the defaults if there are none
+func (c *MCPConfig) ollamaConfig() ollama.Config {
+	if c == nil || c.Providers == nil || c.Providers.Ollama == nil {
+		return ollama.Config{}
+	}
+	return c.Providers.Ollama.Config
+}
+
+// providerConfig returns the settings of a provider, or the defaults if
+// there are none
+func (c *MCPConfig) providerConfig(provider string) ProviderConfig {
+	if c == nil || c.Providers == nil {
+		return ProviderConfig{}
+	}
+	switch provider {
+	case "anthropic":
+		if c.Providers.Anthropic != nil {
+			return *c.Providers.Anthropic
+		}
+	case "openai":
+		if c.Providers.OpenAI != nil {
+			return c.Providers.OpenAI.ProviderConfig
+		}
+	case "google":
+		if c.Providers.Google != nil {
+			return *c.Providers.Google
+		}
+	case "ollama":
+		if c.Providers.Ollama != nil {
+			return c.Providers.Ollama.ProviderConfig
+		}
+	}
+	return ProviderConfig{}
+}
+
+// providerHeaders returns the extra headers and query parameters for a
+// provider's requests
+func (c *MCPConfig) providerHeaders(provider string) *httpclient.Headers {
+	pc := c.providerConfig(provider)
+	headers := &httpclient.Headers{
+		Static:     make(map[string]string),
+		Query:      pc.QueryParams,
+		Command:    pc.HeadersCommand,
+		CommandTTL: time.Duration(pc.HeadersCommandTTL),
+	}
+	for name, value := range pc.Headers {
+		headers.Static[name] = value
+	}
+	if provider == "openai" && c != nil && c.Providers != nil && c.Providers.OpenAI != nil {
+		if org := c.Providers.OpenAI.Organization; org != "" {
+			headers.Static["OpenAI-Organization"] = org
+		}
+		if project := c.Providers.OpenAI.Project; project != "" {
+			headers.Static["OpenAI-Project"] = project
+		}
+	}
+	return headers
+}
+
+// httpClient builds the HTTP client for an endpoint by applying its own
+// settings over the global ones. It returns nil when neither is set, so that
+// callers keep their default client.
+func (c *MCPConfig) httpClient(endpoint *httpclient.Config) (*http.Client, error) {
+	var cfg httpclient.Config
+	if c != nil && c.HTTP != nil {
+		cfg = *c.HTTP
+	}
+	if endpoint != nil {
+		cfg = cfg.Merge(*endpoint)
+	}
+	if cfg.IsZero() {
+		return nil, nil
+	}
+	return httpclient.New(cfg)
 }
 
 type ServerConfig interface {
 	GetType() string
+	GetOptions() ServerOptions
+}
+
+// ServerOptions holds the settings every server entry accepts, whatever
+// its transport
+type ServerOptions struct {
+	// Sampling controls the server's requests to run the model
+	Sampling *ServerSamplingConfig `json:"sampling,omitempty"`
+	// LogFile receives the server's log messages instead of the terminal
+	LogFile string `json:"logFile,omitempty"`
+	// Timeouts limit how long requests to the server may take
+	Timeouts *ServerTimeouts `json:"timeouts,omitempty"`
+	// Disabled keeps the server from being started
+	Disabled bool `json:"disabled,omitempty"`
+	// AllowedTools are glob patterns; if set, only matching tools are
+	// offered to the model
+	AllowedTools []string `json:"allowedTools,omitempty"`
+	// DisabledTools are glob patterns of tools not offered to the model
+	DisabledTools []string `json:"disabledTools,omitempty"`
+}
+
+func (o ServerOptions) GetOptions() ServerOptions {
+	return o
+}
+
+// Default timeouts for requests to servers
+const (
+	defaultInitializeTimeout = 30 * time.Second
+	defaultListTimeout       = 10 * time.Second
+	defaultCallTimeout       = 5 * time.Minute
+)
+
+type ServerTimeouts struct {
+	// Initialize limits connecting to the server
+	Initialize httpclient.Duration `json:"initialize,omitempty"`
+	// List limits listing the server's tools
+	List httpclient.Duration `json:"list,omitempty"`
+	// Call limits a tool call
+	Call httpclient.Duration `json:"call,omitempty"`
+	// Tools overrides Call for single tools, by tool name
+	Tools map[string]httpclient.Duration `json:"tools,omitempty"`
+}
+
+func (t *ServerTimeouts) initializeTimeout() time.Duration {
+	if t == nil || t.Initialize <= 0 {
+		return defaultInitializeTimeout
+	}
+	return time.Duration(t.Initialize)
+}
+
+func (t *ServerTimeouts) listTimeout() time.Duration {
+	if t == nil || t.List <= 0 {
+		return defaultListTimeout
+	}
+	return time.Duration(t.List)
+}
+
+// callTimeout returns the timeout for calling a tool, its own if it has one
+func (t *ServerTimeouts) callTimeout(tool string) time.Duration {
+	if t == nil {
+		return defaultCallTimeout
+	}
+	if timeout, ok := t.Tools[tool]; ok && timeout > 0 {
+		return time.Duration(timeout)
+	}
+	if t.Call > 0 {
+		return time.Duration(t.Call)
+	}
+	return defaultCallTimeout
+}
+
+type ServerSamplingConfig struct {
+	// AutoApprove runs the server's sampling requests without asking
+	AutoApprove bool `json:"autoApprove,omitempty"`
 }
 
 type STDIOServerConfig struct {
+	ServerOptions
+	Type    string            `json:"type,omitempty"`
 	Command string            `json:"command"`
 	Args    []string          `json:"args"`
 	Env     map[string]string `json:"env,omitempty"`
@@ -84,44 +305,70 @@ func (s STDIOServerConfig) GetType() string {
 }
 
 type SSEServerConfig struct {
-	Url     string   `json:"url"`
-	Headers []string `json:"headers,omitempty"`
+	ServerOptions
+	Type    string             `json:"type,omitempty"`
+	Url     string             `json:"url"`
+	Headers []string           `json:"headers,omitempty"`
+	HTTP    *httpclient.Config `json:"http,omitempty"`
 }
 
 func (s SSEServerConfig) GetType() string {
 	return transportSSE
 }
 
+// StreamableHTTPServerConfig is a remote server using the Streamable HTTP
+// transport. It must be selected with "type": "http".
+type StreamableHTTPServerConfig struct {
+	ServerOptions
+	Type    string             `json:"type,omitempty"`
+	Url     string             `json:"url"`
+	Headers []string           `json:"headers,omitempty"`
+	HTTP    *httpclient.Config `json:"http,omitempty"`
+}
+
+func (s StreamableHTTPServerConfig) GetType() string {
+	return transportHTTP
+}
+
 type ServerConfigWrapper struct {
 	Config ServerConfig
 }
 
+// UnmarshalJSON picks the config type from the type field; see
+// serverConfigType. Unknown keys are rejected by parseMCPConfig.
 func (w *ServerConfigWrapper) UnmarshalJSON(data []byte) error {
-	var typeField struct {
-		Url string `json:"url"`
-	}
-
-	if err := json.Unmarshal(data, &typeField); err != nil {
+	serverType, err := serverConfigType(data)
+	if err != nil {
 		return err
 	}
-	if typeField.Url != "" {
-		// If the URL field is present, treat it as an SSE server
+
+	switch serverType {
+	case transportHTTP:
+		var streamable StreamableHTTPServerConfig
+		if err := json.Unmarshal(data, &streamable); err != nil {
+			return err
+		}
+		streamable.Type = serverType
+		w.Config = streamable
+	case transportSSE:
 		var sse SSEServerConfig
 		if err := json.Unmarshal(data, &sse); err != nil {
 			return err
 		}
+		sse.Type = serverType
 		w.Config = sse
-	} else {
-		// Otherwise, treat it as a STDIOServerConfig
+	default:
 		var stdio STDIOServerConfig
 		if err := json.Unmarshal(data, &stdio); err != nil {
 			return err
 		}
+		stdio.Type = serverType
 		w.Config = stdio
 	}
 
 	return nil
 }
+
 func (w ServerConfigWrapper) MarshalJSON() ([]byte, error) {
 	return json.Marshal(w.Config)
 }
@@ -149,16 +396,36 @@ func mcpToolsToAnthropicTools(
 	return anthropicTools
 }
 
-func loadMCPConfig() (*MCPConfig, error) {
-	var configPath string
-	if configFile != "" {
-		configPath = configFile
-	} else {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return nil, fmt.Errorf("error getting home directory: %w", err)
+// parseHeaders turns "Name: value" strings from a server config into a map
+func parseHeaders(headers []string) map[string]string {
+	parsed := make(map[string]string)
+	for _, header := range headers {
+		parts := strings.SplitN(header, ":", 2)
+		if len(parts) == 2 {
+			key := strings.TrimSpace(parts[0])
+			value := strings.TrimSpace(parts[1])
+			parsed[key] = value
 		}
-		configPath = filepath.Join(homeDir, ".mcp.json")
+	}
+	return parsed
+}
+
+// configPath returns the config file given with --config, or ~/.mcp.json
+func configPath() (string, error) {
+	if configFile != "" {
+		return configFile, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".mcp.json"), nil
+}
+
+func loadMCPConfig() (*MCPConfig, error) {
+	configPath, err := configPath()
+	if err != nil {
+		return nil, err
 	}
 
 	// Check if config file exists
@@ -192,127 +459,211 @@ func loadMCPConfig() (*MCPConfig, error) {
 		)
 	}
 
-	var config MCPConfig
-	if err := json.Unmarshal(configData, &config); err != nil {
-		return nil, fmt.Errorf("error parsing config file: %w", err)
+	config, err := parseMCPConfig(configData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s:\n%w", configPath, err)
 	}
 
-	return &config, nil
+	return config, nil
 }
 
+// createMCPClients connects to every configured server at once. Servers
+// that fail to start are kept in the failed state with their error, so that
+// they show up in /servers and can be reconnected. clientOptions returns the
+// options for a server's client, such as the handlers for requests from the
+// server; it may be nil.
 func createMCPClients(
 	config *MCPConfig,
-) (map[string]mcpclient.MCPClient, error) {
+	clientOptions func(server string) []mcpclient.ClientOption,
+) map[string]mcpclient.MCPClient {
 	clients := make(map[string]mcpclient.MCPClient)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
 
 	for name, server := range config.MCPServers {
-		var client mcpclient.MCPClient
-		var err error
-
-		if server.Config.GetType() == transportSSE {
-			sseConfig := server.Config.(SSEServerConfig)
-
-			var options []transport.ClientOption
-
-			if sseConfig.Headers != nil {
-				// Parse headers from the config
-				headers := make(map[string]string)
-				for _, header := range sseConfig.Headers {
-					parts := strings.SplitN(header, ":", 2)
-					if len(parts) == 2 {
-						key := strings.TrimSpace(parts[0])
-						value := strings.TrimSpace(parts[1])
-						headers[key] = value
-					}
-				}
-				options = append(options, transport.WithHeaders(headers))
-			}
-
-			sseClient, err := mcpclient.NewSSEMCPClient(
-				sseConfig.Url,
-				options...,
-			)
-			if err == nil {
-				err = sseClient.Start(context.Background())
-				if err == nil {
-					client = sseClient
-				}
+		if server.Config.GetOptions().Disabled {
+			log.Info("Server disabled", "name", name)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			connect := func() (*mcpclient.Client, error) {
+				return connectServer(config, name, server, clientOptions)
 			}
-		} else {
-			stdioConfig := server.Config.(STDIOServerConfig)
-			var env []string
-			for k, v := range stdioConfig.Env {
-				env = append(env, fmt.Sprintf("%s=%s", k, v))
+			client := newSupervisedClient(name, connect)
+			if _, err := client.State(); err != nil {
+				log.Error("Server failed to start", "name", name, "error", err)
 			}
-			client, err = mcpclient.NewStdioMCPClient(
-				stdioConfig.Command,
-				env,
-				stdioConfig.Args...)
+			mu.Lock()
+			clients[name] = client
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return clients
+}
+
+// connectServer starts the client of one server and initializes it
+func connectServer(
+	config *MCPConfig,
+	name string,
+	server ServerConfigWrapper,
+	clientOptions func(server string) []mcpclient.ClientOption,
+) (*mcpclient.Client, error) {
+	var client *mcpclient.Client
+	var err error
+
+	var clientOpts []mcpclient.ClientOption
+	if clientOptions != nil {
+		clientOpts = clientOptions(name)
+	}
+
+	switch server.Config.GetType() {
+	case transportSSE:
+		sseConfig := server.Config.(SSEServerConfig)
+
+		var options []transport.ClientOption
+
+		if sseConfig.Headers != nil {
+			options = append(options, transport.WithHeaders(parseHeaders(sseConfig.Headers)))
 		}
-		if err != nil {
-			for _, c := range clients {
-				c.Close()
-			}
-			return nil, fmt.Errorf(
-				"failed to create MCP client for %s: %w",
-				name,
-				err,
-			)
+
+		httpClient, httpErr := config.httpClient(sseConfig.HTTP)
+		if httpErr != nil {
+			return nil, fmt.Errorf("invalid HTTP settings for %s: %w", name, httpErr)
+		}
+		if httpClient != nil {
+			options = append(options, transport.WithHTTPClient(httpClient))
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
+		var sseTransport *transport.SSE
+		sseTransport, err = transport.NewSSE(sseConfig.Url, options...)
+		if err == nil {
+			client, err = startMCPClient(sseTransport, clientOpts...)
+		}
+	case transportHTTP:
+		httpConfig := server.Config.(StreamableHTTPServerConfig)
 
-		log.Info("Initializing server...", "name", name)
-		initRequest := mcp.InitializeRequest{}
-		initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
-		initRequest.Params.ClientInfo = mcp.Implementation{
-			Name:    "mcphost",
-			Version: "0.1.0",
+		httpClient, httpErr := config.httpClient(httpConfig.HTTP)
+		if httpErr != nil {
+			return nil, fmt.Errorf("invalid HTTP settings for %s: %w", name, httpErr)
 		}
-		initRequest.Params.Capabilities = mcp.ClientCapabilities{}
 
-		_, err = client.Initialize(ctx, initRequest)
-		if err != nil {
-			client.Close()
-			for _, c := range clients {
-				c.Close()
-			}
-			return nil, fmt.Errorf(
-				"failed to initialize MCP client for %s: %w",
-				name,
-				err,
-			)
+		// The session ID and protocol version headers are handled by
+		// the transport; we add resuming of broken event streams and
+		// the GET stream for server initiated messages
+		options := []transport.StreamableHTTPCOption{
+			transport.WithHTTPBasicClient(httpclient.Resumable(httpClient)),
+			transport.WithContinuousListening(),
+			transport.WithHTTPLogger(transportLogger{server: name}),
+		}
+		if httpConfig.Headers != nil {
+			options = append(options, transport.WithHTTPHeaders(parseHeaders(httpConfig.Headers)))
 		}
 
-		clients[name] = client
+		var httpTransport *transport.StreamableHTTP
+		httpTransport, err = transport.NewStreamableHTTP(httpConfig.Url, options...)
+		if err == nil {
+			client, err = startMCPClient(httpTransport, clientOpts...)
+		}
+	default:
+		stdioConfig := server.Config.(STDIOServerConfig)
+		var env []string
+		for k, v := range stdioConfig.Env {
+			env = append(env, fmt.Sprintf("%s=%s", k, v))
+		}
+		client, err = startMCPClient(transport.NewStdioWithOptions(
+			stdioConfig.Command,
+			env,
+			stdioConfig.Args,
+			transport.WithCommandLogger(transportLogger{server: name}),
+		), clientOpts...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to create MCP client for %s: %w",
+			name,
+			err,
+		)
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		server.Config.GetOptions().Timeouts.initializeTimeout(),
+	)
+	defer cancel()
+
+	log.Info("Initializing server...", "name", name)
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initRequest.Params.ClientInfo = mcp.Implementation{
+		Name:    "mcphost",
+		Version: "0.1.0",
+	}
+	initRequest.Params.Capabilities = mcp.ClientCapabilities{}
+
+	_, err = client.Initialize(ctx, initRequest)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf(
+			"failed to initialize MCP client for %s: %w",
+			name,
+			err,
+		)
 	}
 
-	return clients, nil
+	return client, nil
 }
 
 func handleSlashCommand(
+	ctx context.Context,
 	prompt string,
-	mcpConfig *MCPConfig,
-	mcpClients map[string]mcpclient.MCPClient,
-	messages interface{},
+	ms *MCPSession,
+	messages *[]history.HistoryMessage,
 ) (bool, error) {
 	if !strings.HasPrefix(prompt, "/") {
 		return false, nil
 	}
 
-	switch strings.ToLower(strings.TrimSpace(prompt)) {
+	fields := strings.Fields(prompt)
+	command := strings.ToLower(fields[0])
+
+	switch command {
 	case "/tools":
-		handleToolsCommand(mcpClients)
+		if len(fields) > 1 && (fields[1] == "enable" || fields[1] == "disable") {
+			handleToolsToggleCommand(ms, fields[1:])
+		} else {
+			handleToolsCommand(ms)
+		}
+		return true, nil
+	case "/resources":
+		handleResourcesCommand(ms.MCPClients)
 		return true, nil
 	case "/help":
-		handleHelpCommand()
+		handleHelpCommand(ctx, ms)
 		return true, nil
 	case "/history":
-		handleHistoryCommand(messages.([]history.HistoryMessage))
+		handleHistoryCommand(*messages)
 		return true, nil
 	case "/servers":
-		handleServersCommand(mcpConfig)
+		handleServersCommand(ms)
+		return true, nil
+	case "/roots":
+		handleRootsCommand(ctx, ms, fields[1:])
+		return true, nil
+	case "/reconnect":
+		handleReconnectCommand(ms, fields[1:])
+		return true, nil
+	case "/loglevel":
+		handleLogLevelCommand(ctx, ms, fields[1:])
+		return true, nil
+	case "/compact":
+		handleCompactCommand(ctx, ms, messages)
+		return true, nil
+	case "/toolchoice":
+		handleToolChoiceCommand(ms, fields[1:])
 		return true, nil
 	case "/quit":
 		fmt.Println("\nGoodbye!")
@@ -325,7 +676,7 @@ func handleSlashCommand(
 	}
 }
 
-func handleHelpCommand() {
+func handleHelpCommand(ctx context.Context, ms *MCPSession) {
 	if err := updateRenderer(); err != nil {
 		fmt.Printf(
 			"\n%s\n",
@@ -339,10 +690,35 @@ func handleHelpCommand() {
 	markdown.WriteString("The following commands are available:\n\n")
 	markdown.WriteString("- **/help**: Show this help message\n")
 	markdown.WriteString("- **/tools**: List all available tools\n")
-	markdown.WriteString("- **/servers**: List configured MCP servers\n")
+	markdown.WriteString("- **/tools enable|disable <pattern>**: Offer or hide the tools matching a server__tool glob pattern\n")
+	markdown.WriteString("- **/resources**: List resources and resource templates from all servers\n")
+	markdown.WriteString("- **/servers**: List configured MCP servers and their state\n")
+	markdown.WriteString("- **/reconnect <name>**: Restart or reconnect a server, such as one that failed to start\n")
+	markdown.WriteString("- **/roots [add|remove <dir>]**: Show or change the directories offered to servers\n")
+	markdown.WriteString("- **/loglevel [level]**: Show or set the level of server log messages shown\n")
 	markdown.WriteString("- **/history**: Display conversation history\n")
+	markdown.WriteString("- **/compact**: Summarize older messages to free up context\n")
+	markdown.WriteString("- **/toolchoice [auto|none|required|server__tool]**: Show or set how tools are used\n")
 	markdown.WriteString("- **/quit**: Exit the application\n")
-	markdown.WriteString("\nYou can also press Ctrl+C at any time to quit.\n")
+	markdown.WriteString("- **/server:prompt [name=value ...]**: Run a prompt published by a server, asking for its arguments\n")
+	markdown.WriteString("\nMention **@server:uri** in a prompt to attach that resource to the message.\n")
+	markdown.WriteString("\nPress Esc or Ctrl+C while the model or a tool is running to cancel the current turn, or Ctrl+C at the prompt to quit.\n")
+
+	var prompts []serverPrompt
+	_ = spinner.New().
+		Title("Fetching prompts from all servers...").
+		Action(func() { prompts = ms.listServerPrompts(ctx) }).
+		Run()
+	if len(prompts) > 0 {
+		markdown.WriteString("\n## Server Prompts\n\n")
+		for _, p := range prompts {
+			markdown.WriteString(fmt.Sprintf("- **%s**", p.command()))
+			if p.prompt.Description != "" {
+				markdown.WriteString(": " + p.prompt.Description)
+			}
+			markdown.WriteString("\n")
+		}
+	}
 
 	markdown.WriteString("\n## Available Models\n\n")
 	markdown.WriteString("Specify models using the --model or -m flag:\n\n")
@@ -368,7 +744,21 @@ func handleHelpCommand() {
 	fmt.Print(rendered)
 }
 
-func handleServersCommand(config *MCPConfig) {
+func handleToolChoiceCommand(ms *MCPSession, args []string) {
+	if len(args) == 0 {
+		fmt.Printf("\n%s\n\n", promptStyle.Render("Tool choice: "+ms.ToolChoice.String()))
+		return
+	}
+
+	if err := ms.SetToolChoice(llm.ParseToolChoice(args[0])); err != nil {
+		fmt.Printf("\n%s\n\n", errorStyle.Render(fmt.Sprintf("Error setting tool choice: %v", err)))
+		return
+	}
+	fmt.Printf("\n%s\n\n", promptStyle.Render("Tool choice set to "+ms.ToolChoice.String()))
+}
+
+func handleServersCommand(ms *MCPSession) {
+	config := ms.Config
 	if err := updateRenderer(); err != nil {
 		fmt.Printf(
 			"\n%s\n",
@@ -385,24 +775,25 @@ func handleServersCommand(config *MCPConfig) {
 			for name, server := range config.MCPServers {
 				markdown.WriteString(fmt.Sprintf("# %s\n\n", name))
 
-				if server.Config.GetType() == transportSSE {
-					sseConfig := server.Config.(SSEServerConfig)
-					markdown.WriteString("*Url*\n")
-					markdown.WriteString(fmt.Sprintf("`%s`\n\n", sseConfig.Url))
-					markdown.WriteString("*headers*\n")
-					if sseConfig.Headers != nil {
-						for _, header := range sseConfig.Headers {
-							parts := strings.SplitN(header, ":", 2)
-							if len(parts) == 2 {
-								key := strings.TrimSpace(parts[0])
-								markdown.WriteString("`" + key + ": [REDACTED]`\n")
-							}
-						}
-					} else {
-						markdown.WriteString("*None*\n")
+				if server.Config.GetOptions().Disabled {
+					markdown.WriteString("*State*\n`disabled`\n\n")
+				} else if client, ok := ms.MCPClients[name].(*supervisedClient); ok {
+					state, err := client.State()
+					markdown.WriteString("*State*\n")
+					markdown.WriteString(fmt.Sprintf("`%s`\n\n", state))
+					if err != nil {
+						markdown.WriteString(fmt.Sprintf("*Last error*\n%v\n\n", err))
 					}
+				}
 
-				} else {
+				switch server.Config.GetType() {
+				case transportSSE:
+					sseConfig := server.Config.(SSEServerConfig)
+					writeRemoteServer(&markdown, "SSE", sseConfig.Url, sseConfig.Headers)
+				case transportHTTP:
+					httpConfig := server.Config.(StreamableHTTPServerConfig)
+					writeRemoteServer(&markdown, "Streamable HTTP", httpConfig.Url, httpConfig.Headers)
+				default:
 					stdioConfig := server.Config.(STDIOServerConfig)
 					markdown.WriteString("*Command*\n")
 					markdown.WriteString(fmt.Sprintf("`%s`\n\n", stdioConfig.Command))
@@ -443,7 +834,23 @@ func handleServersCommand(config *MCPConfig) {
 	fmt.Print("\n" + containerStyle.Render(rendered) + "\n")
 }
 
-func handleToolsCommand(mcpClients map[string]mcpclient.MCPClient) {
+// writeRemoteServer describes a remote server for /servers, hiding header values
+func writeRemoteServer(markdown *strings.Builder, transportName, url string, headers []string) {
+	markdown.WriteString("*Transport*\n")
+	markdown.WriteString(fmt.Sprintf("`%s`\n\n", transportName))
+	markdown.WriteString("*Url*\n")
+	markdown.WriteString(fmt.Sprintf("`%s`\n\n", url))
+	markdown.WriteString("*headers*\n")
+	if headers == nil {
+		markdown.WriteString("*None*\n")
+		return
+	}
+	for name := range parseHeaders(headers) {
+		markdown.WriteString("`" + name + ": [REDACTED]`\n")
+	}
+}
+
+func handleToolsCommand(ms *MCPSession) {
 	// Get terminal width for proper wrapping
 	width := getTerminalWidth()
 
@@ -451,7 +858,7 @@ func handleToolsCommand(mcpClients map[string]mcpclient.MCPClient) {
 	contentWidth := width - 12 // Account for margins and list markers
 
 	// If tools are disabled (empty client map), show a message
-	if len(mcpClients) == 0 {
+	if len(ms.MCPClients) == 0 {
 		fmt.Print(
 			"\n" + contentStyle.Render(
 				"Tools are currently disabled for this model.\n",
@@ -460,50 +867,20 @@ func handleToolsCommand(mcpClients map[string]mcpclient.MCPClient) {
 		return
 	}
 
-	type serverTools struct {
-		tools []mcp.Tool
-		err   error
-	}
-	results := make(map[string]serverTools)
-
-	action := func() {
-		for serverName, mcpClient := range mcpClients {
-			ctx, cancel := context.WithTimeout(
-				context.Background(),
-				10*time.Second,
-			)
-			defer cancel()
-
-			toolsResult, err := mcpClient.ListTools(ctx, mcp.ListToolsRequest{})
-			if err != nil {
-				results[serverName] = serverTools{
-					tools: nil,
-					err:   err,
-				}
-				continue
-			}
-
-			var tools []mcp.Tool
-			if toolsResult != nil {
-				tools = toolsResult.Tools
-			}
-
-			results[serverName] = serverTools{
-				tools: tools,
-				err:   nil,
-			}
-		}
+	// The catalog is kept up to date as servers report changes
+	results := ms.tools.snapshot()
+	serverNames := make([]string, 0, len(results))
+	for name := range results {
+		serverNames = append(serverNames, name)
 	}
-	_ = spinner.New().
-		Title("Fetching tools from all servers...").
-		Action(action).
-		Run()
+	sort.Strings(serverNames)
 
 	// Create a list for all servers
 	l := list.New().
 		EnumeratorStyle(lipgloss.NewStyle().Foreground(tokyoPurple).MarginRight(1))
 
-	for serverName, result := range results {
+	for _, serverName := range serverNames {
+		result := results[serverName]
 		if result.err != nil {
 			fmt.Printf(
 				"\n%s\n",
@@ -537,8 +914,13 @@ func handleToolsCommand(mcpClients map[string]mcpclient.MCPClient) {
 					EnumeratorStyle(lipgloss.NewStyle().Foreground(tokyoGreen).MarginRight(1)).
 					Item(descStyle.Render(tool.Description))
 
+				name := toolNameStyle.Render(tool.Name)
+				if !ms.toolEnabled(serverName, tool.Name) {
+					name += " " + spinnerHintStyle.Render("(disabled)")
+				}
+
 				// Add the tool with its description as a nested list
-				serverList.Item(toolNameStyle.Render(tool.Name)).
+				serverList.Item(name).
 					Item(toolDesc)
 			}
 		}
@@ -582,6 +964,12 @@ func displayMessageHistory(messages []history.HistoryMessage) {
 				markdown.WriteString("### Text\n")
 				markdown.WriteString(block.Text + "\n\n")
 
+			case "resource":
+				markdown.WriteString("### Resource\n")
+				markdown.WriteString("```\n")
+				markdown.WriteString(block.Text)
+				markdown.WriteString("\n```\n\n")
+
 			case "tool_use":
 				markdown.WriteString("### Tool Use\n")
 				markdown.WriteString(
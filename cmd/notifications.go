@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/log"
+	mcpclient "github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// setLevelTimeout bounds asking one server to change its log level
+const setLevelTimeout = 5 * time.Second
+
+// serverLogLevels are the MCP log levels, least severe first
+var serverLogLevels = []mcp.LoggingLevel{
+	mcp.LoggingLevelDebug,
+	mcp.LoggingLevelInfo,
+	mcp.LoggingLevelNotice,
+	mcp.LoggingLevelWarning,
+	mcp.LoggingLevelError,
+	mcp.LoggingLevelCritical,
+	mcp.LoggingLevelAlert,
+	mcp.LoggingLevelEmergency,
+}
+
+// watchNotifications handles the notifications a server sends
+func (ms *MCPSession) watchNotifications(serverName string, client mcpclient.MCPClient) {
+	client.OnNotification(func(notification mcp.JSONRPCNotification) {
+		switch notification.Method {
+		case mcp.MethodNotificationToolsListChanged:
+			// Notifications arrive on the goroutine that reads responses, so
+			// the new list has to be requested from another one
+			go ms.reloadTools(serverName, client)
+		case "notifications/progress":
+			var params mcp.ProgressNotificationParams
+			if err := decodeNotification(notification, &params); err != nil {
+				log.Debug("Invalid progress notification", "server", serverName, "error", err)
+				return
+			}
+			ms.progress.update(params)
+		case "notifications/message":
+			var params mcp.LoggingMessageNotificationParams
+			if err := decodeNotification(notification, &params); err != nil {
+				log.Debug("Invalid log notification", "server", serverName, "error", err)
+				return
+			}
+			ms.logServerMessage(serverName, params)
+		}
+	})
+}
+
+// decodeNotification decodes the params of a notification into v
+func decodeNotification(notification mcp.JSONRPCNotification, v any) error {
+	data, err := json.Marshal(notification.Params)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// progressTracker hands out progress tokens for requests and passes the
+// progress notifications for them on
+type progressTracker struct {
+	mu       sync.Mutex
+	next     int
+	handlers map[string]func(mcp.ProgressNotificationParams)
+}
+
+// track returns a new progress token whose notifications go to handler,
+// and a function to call once the request is done
+func (t *progressTracker) track(handler func(mcp.ProgressNotificationParams)) (mcp.ProgressToken, func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.handlers == nil {
+		t.handlers = make(map[string]func(mcp.ProgressNotificationParams))
+	}
+	t.next++
+	token := fmt.Sprintf("mcphost-%d", t.next)
+	t.handlers[token] = handler
+	return token, func() {
+		t.mu.Lock()
+		delete(t.handlers, token)
+		t.mu.Unlock()
+	}
+}
+
+func (t *progressTracker) update(params mcp.ProgressNotificationParams) {
+	t.mu.Lock()
+	handler := t.handlers[fmt.Sprint(params.ProgressToken)]
+	t.mu.Unlock()
+	if handler != nil {
+		handler(params)
+	}
+}
+
+// describeProgress formats a progress notification for the spinner
+func describeProgress(params mcp.ProgressNotificationParams) string {
+	var status string
+	if params.Total > 0 {
+		status = fmt.Sprintf("%.0f%%", params.Progress/params.Total*100)
+	} else {
+		status = fmt.Sprintf("%g", params.Progress)
+	}
+	if params.Message != "" {
+		status += " " + params.Message
+	}
+	return status
+}
+
+// LogLevel returns the least severe server log level that is shown
+func (ms *MCPSession) LogLevel() mcp.LoggingLevel {
+	ms.logMu.Lock()
+	defer ms.logMu.Unlock()
+	if ms.logLevel == "" {
+		return mcp.LoggingLevelInfo
+	}
+	return ms.logLevel
+}
+
+// SetLogLevel changes the server log level and asks every server that logs
+// to send only messages at that level or above. Servers that fail to
+// change are returned by name with their errors.
+func (ms *MCPSession) SetLogLevel(ctx context.Context, level mcp.LoggingLevel) map[string]error {
+	ms.logMu.Lock()
+	ms.logLevel = level
+	ms.logMu.Unlock()
+
+	failed := make(map[string]error)
+	for serverName, client := range ms.MCPClients {
+		if !hasLogging(client) {
+			continue
+		}
+		setCtx, cancel := context.WithTimeout(ctx, setLevelTimeout)
+		req := mcp.SetLevelRequest{}
+		req.Params.Level = level
+		if err := client.SetLevel(setCtx, req); err != nil {
+			failed[serverName] = err
+		}
+		cancel()
+	}
+	return failed
+}
+
+// hasLogging reports whether a server sends log messages. Clients that do
+// not know are assumed to.
+func hasLogging(client mcpclient.MCPClient) bool {
+	if c, ok := client.(capabilitiesClient); ok {
+		return c.GetServerCapabilities().Logging != nil
+	}
+	return true
+}
+
+// logServerMessage shows a log message from a server if it is at or above
+// the log level. Servers are asked to filter too, but need not listen.
+func (ms *MCPSession) logServerMessage(serverName string, params mcp.LoggingMessageNotificationParams) {
+	if !params.Level.ShouldSendTo(ms.LogLevel()) {
+		return
+	}
+
+	msg, ok := params.Data.(string)
+	if !ok {
+		data, err := json.Marshal(params.Data)
+		if err != nil {
+			msg = fmt.Sprint(params.Data)
+		} else {
+			msg = string(data)
+		}
+	}
+	var keyvals []interface{}
+	if params.Logger != "" {
+		keyvals = append(keyvals, "logger", params.Logger)
+	}
+
+	logger := ms.serverLogger(serverName)
+	switch params.Level {
+	case mcp.LoggingLevelDebug:
+		logger.Debug(msg, keyvals...)
+	case mcp.LoggingLevelInfo, mcp.LoggingLevelNotice:
+		logger.Info(msg, keyvals...)
+	case mcp.LoggingLevelWarning:
+		logger.Warn(msg, keyvals...)
+	default:
+		logger.Error(msg, keyvals...)
+	}
+}
+
+// serverLogger returns the logger for a server's log messages: its log
+// file if one is configured, otherwise the terminal
+func (ms *MCPSession) serverLogger(serverName string) *log.Logger {
+	ms.logMu.Lock()
+	defer ms.logMu.Unlock()
+	if logger, ok := ms.serverLogs[serverName]; ok {
+		return logger
+	}
+	if ms.serverLogs == nil {
+		ms.serverLogs = make(map[string]*log.Logger)
+	}
+
+	var logger *log.Logger
+	if path := ms.serverOptions(serverName).LogFile; path != "" {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			log.Warn("Cannot open server log file, logging to the terminal", "server", serverName, "error", err)
+		} else {
+			ms.logFiles = append(ms.logFiles, file)
+			logger = log.NewWithOptions(file, log.Options{
+				ReportTimestamp: true,
+				Level:           log.DebugLevel,
+			})
+		}
+	}
+	if logger == nil {
+		logger = log.NewWithOptions(terminalWriter{}, log.Options{
+			Prefix: serverName,
+			Level:  log.DebugLevel,
+		})
+		// The writer hides the terminal, so colors are not detected
+		logger.SetColorProfile(lipgloss.ColorProfile())
+	}
+	ms.serverLogs[serverName] = logger
+	return logger
+}
+
+// closeServerLogs closes the servers' log files
+func (ms *MCPSession) closeServerLogs() {
+	ms.logMu.Lock()
+	defer ms.logMu.Unlock()
+	for _, file := range ms.logFiles {
+		file.Close()
+	}
+	ms.logFiles = nil
+	ms.serverLogs = nil
+}
+
+func handleLogLevelCommand(ctx context.Context, ms *MCPSession, args []string) {
+	if len(args) == 0 {
+		fmt.Printf("\n%s\n\n", promptStyle.Render(fmt.Sprintf("Server log level: %s", ms.LogLevel())))
+		return
+	}
+
+	level := mcp.LoggingLevel(strings.ToLower(args[0]))
+	valid := false
+	names := make([]string, len(serverLogLevels))
+	for i, l := range serverLogLevels {
+		names[i] = string(l)
+		valid = valid || l == level
+	}
+	if len(args) > 1 || !valid {
+		fmt.Printf("\n%s\n\n", errorStyle.Render("Usage: /loglevel ["+strings.Join(names, "|")+"]"))
+		return
+	}
+
+	for serverName, err := range ms.SetLogLevel(ctx, level) {
+		fmt.Printf("\n%s\n", errorStyle.Render(fmt.Sprintf("Error setting log level of %s: %v", serverName, err)))
+	}
+	fmt.Printf("\n%s\n\n", promptStyle.Render(fmt.Sprintf("Server log level set to %s", level)))
+}
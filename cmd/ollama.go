@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/huh/spinner"
+	"github.com/charmbracelet/log"
+	"github.com/mark3labs/mcphost/pkg/llm/ollama"
+	"github.com/ollama/ollama/api"
+)
+
+// prepareOllamaModel makes sure the model is available on the Ollama server,
+// pulling it if needed, and loads it so that the first prompt is not delayed
+func (ms *MCPSession) prepareOllamaModel(
+	ctx context.Context,
+	provider *ollama.Provider,
+	model string,
+) error {
+	exists, err := provider.ModelExists(ctx)
+	if err != nil {
+		return fmt.Errorf("error checking Ollama model: %v", err)
+	}
+
+	if !exists {
+		if !ms.Config.ollamaConfig().AutoPull {
+			if !ms.InTerminal {
+				return fmt.Errorf(
+					"model %s is not available in Ollama, run `ollama pull %s` or set providers.ollama.autoPull in the config file",
+					model,
+					model,
+				)
+			}
+
+			pull := true
+			err := huh.NewConfirm().
+				Title(fmt.Sprintf("Model %s is not available in Ollama. Pull it now?", model)).
+				Value(&pull).
+				Run()
+			if err != nil {
+				return err
+			}
+			if !pull {
+				return fmt.Errorf("model %s is not available in Ollama", model)
+			}
+		}
+
+		log.Info("Pulling model", "model", model)
+		if err := provider.Pull(ctx, printPullProgress); err != nil {
+			fmt.Fprintln(os.Stderr)
+			return fmt.Errorf("error pulling model %s: %v", model, err)
+		}
+		fmt.Fprintln(os.Stderr)
+	}
+
+	preload := func() {
+		err = provider.Preload(ctx)
+	}
+	if ms.InTerminal {
+		_ = spinner.New().
+			Title(fmt.Sprintf("Loading %s...", model)).
+			Action(preload).
+			Run()
+	} else {
+		preload()
+	}
+	if err != nil {
+		return fmt.Errorf("error loading model %s: %v", model, err)
+	}
+	return nil
+}
+
+// printPullProgress renders pull progress on a single line of stderr
+func printPullProgress(progress api.ProgressResponse) error {
+	if progress.Total > 0 {
+		fmt.Fprintf(os.Stderr, "\r\033[K%s %3d%% (%s / %s)",
+			progress.Status,
+			progress.Completed*100/progress.Total,
+			formatBytes(progress.Completed),
+			formatBytes(progress.Total))
+	} else {
+		fmt.Fprintf(os.Stderr, "\r\033[K%s", progress.Status)
+	}
+	return nil
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
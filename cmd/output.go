@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/mark3labs/mcphost/pkg/history"
+	"github.com/mark3labs/mcphost/pkg/jsonschema"
+	"github.com/mark3labs/mcphost/pkg/llm"
+)
+
+// maxOutputRetries is how many times the model is asked to fix an answer
+// that does not match the output schema
+const maxOutputRetries = 2
+
+const outputRetryPrompt = `Your previous reply did not match the required JSON schema: %v
+Reply again with only a JSON value that matches this schema, without any other text:
+%s`
+
+// loadOutputSchema reads a JSON Schema from a file
+func loadOutputSchema(filePath string) (map[string]interface{}, error) {
+	if filePath == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading output schema: %v", err)
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("error parsing output schema: %v", err)
+	}
+	if err := jsonschema.Check(schema); err != nil {
+		return nil, fmt.Errorf("error in output schema: %v", err)
+	}
+
+	return schema, nil
+}
+
+// structuredCandidate extracts the structured answer from a model response.
+// final is false when the model called MCP tools, in which case the tool loop
+// continues and the answer comes later.
+func structuredCandidate(
+	schema map[string]interface{},
+	message llm.Message,
+) (raw string, final bool) {
+	toolCalls := message.GetToolCalls()
+	for _, call := range toolCalls {
+		if call.GetName() == llm.OutputToolName {
+			output, err := json.Marshal(llm.OutputFromToolArguments(schema, call.GetArguments()))
+			if err != nil {
+				return "", true
+			}
+			return string(output), true
+		}
+	}
+	if len(toolCalls) > 0 {
+		return "", false
+	}
+	return stripCodeFence(message.GetContent()), true
+}
+
+// stripCodeFence removes a surrounding markdown code fence, which models
+// like to add even when asked for bare JSON
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+	text = strings.TrimPrefix(text, "```")
+	if i := strings.Index(text, "\n"); i >= 0 {
+		text = text[i+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
+}
+
+// handleStructuredOutput validates the final answer against the output schema,
+// asking the model to correct it if needed. It returns true once the turn is
+// finished, and false if the model called tools and the loop should continue.
+func (ms *MCPSession) handleStructuredOutput(
+	ctx context.Context,
+	message llm.Message,
+	messages *[]history.HistoryMessage,
+	callback func(
+		ctx context.Context,
+		text string,
+		role int,
+		action func(),
+	) error,
+) (bool, error) {
+	raw, final := structuredCandidate(ms.OutputSchema, message)
+	if !final {
+		return false, nil
+	}
+
+	schemaJSON, _ := json.Marshal(ms.OutputSchema)
+	for attempt := 0; ; attempt++ {
+		value, err := jsonschema.ValidateJSON(ms.OutputSchema, []byte(raw))
+		if err == nil {
+			output, _ := json.MarshalIndent(value, "", "  ")
+			*messages = append(*messages, history.HistoryMessage{
+				Role: "assistant",
+				Content: []history.ContentBlock{{
+					Type: "text",
+					Text: string(output),
+				}},
+			})
+			callback(ctx, string(output), MODE_STRUCTURED_OUTPUT, nil)
+			return true, nil
+		}
+
+		if attempt >= maxOutputRetries {
+			return true, fmt.Errorf("response does not match the output schema: %v", err)
+		}
+		log.Warn("Response does not match the output schema, retrying",
+			"attempt", attempt+1,
+			"error", err)
+
+		// Retry without tools, so that providers which cannot combine tools
+		// with a response schema enforce the schema this time
+		llmMessages := ms.requestMessages(*messages)
+		llmMessages = append(llmMessages,
+			&history.HistoryMessage{
+				Role:    "assistant",
+				Content: []history.ContentBlock{{Type: "text", Text: raw}},
+			},
+			&history.HistoryMessage{
+				Role: "user",
+				Content: []history.ContentBlock{{
+					Type: "text",
+					Text: fmt.Sprintf(outputRetryPrompt, err, schemaJSON),
+				}},
+			},
+		)
+
+		var retry llm.Message
+		var retryErr error
+		callback(ctx, "", MODE_CREATE_MESSAGE, func() {
+			retry, retryErr = ms.Provider.CreateMessage(
+				ctx,
+				"",
+				llmMessages,
+				nil,
+				llm.WithOutputSchema(ms.OutputSchema),
+			)
+		})
+		if retryErr != nil {
+			return true, retryErr
+		}
+		raw, _ = structuredCandidate(ms.OutputSchema, retry)
+	}
+}
@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/log"
+	mcpclient "github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcphost/pkg/history"
+)
+
+// promptTimeout bounds listing or getting the prompts of one server
+const promptTimeout = 10 * time.Second
+
+// serverPrompt is a prompt template published by a server, used as the
+// slash command /server:prompt
+type serverPrompt struct {
+	server string
+	prompt mcp.Prompt
+}
+
+func (p serverPrompt) command() string {
+	return "/" + p.server + ":" + p.prompt.Name
+}
+
+// hasPrompts reports whether a server offers prompts. Clients that do not
+// know are assumed to.
+func hasPrompts(client mcpclient.MCPClient) bool {
+	if c, ok := client.(capabilitiesClient); ok {
+		return c.GetServerCapabilities().Prompts != nil
+	}
+	return true
+}
+
+// listServerPrompts returns the prompts of every server, sorted by command.
+// Servers that fail to answer are logged and skipped.
+func (ms *MCPSession) listServerPrompts(ctx context.Context) []serverPrompt {
+	var prompts []serverPrompt
+	for serverName, client := range ms.MCPClients {
+		if !hasPrompts(client) {
+			continue
+		}
+		listCtx, cancel := context.WithTimeout(ctx, promptTimeout)
+		result, err := client.ListPrompts(listCtx, mcp.ListPromptsRequest{})
+		cancel()
+		if err != nil {
+			log.Debug("Failed to list prompts", "server", serverName, "error", err)
+			continue
+		}
+		for _, prompt := range result.Prompts {
+			prompts = append(prompts, serverPrompt{server: serverName, prompt: prompt})
+		}
+	}
+	sort.Slice(prompts, func(i, j int) bool {
+		return prompts[i].command() < prompts[j].command()
+	})
+	return prompts
+}
+
+// parsePromptCommand splits "/server:prompt" into its parts if server is
+// one of ours
+func (ms *MCPSession) parsePromptCommand(input string) (string, string, bool) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return "", "", false
+	}
+	serverName, promptName, ok := strings.Cut(strings.TrimPrefix(fields[0], "/"), ":")
+	if !ok || promptName == "" {
+		return "", "", false
+	}
+	if _, exists := ms.MCPClients[serverName]; !exists {
+		return "", "", false
+	}
+	return serverName, promptName, true
+}
+
+// applyServerPrompt runs the prompt command in input: it asks for the
+// prompt's arguments, gets the prompt from the server and appends the
+// messages it returns. Arguments can also be given inline as name=value.
+// The server is asked through callback, so that the user can cancel.
+func (ms *MCPSession) applyServerPrompt(
+	ctx context.Context,
+	input string,
+	messages *[]history.HistoryMessage,
+	callback func(
+		ctx context.Context,
+		text string,
+		role int,
+		action func(),
+	) error,
+) error {
+	serverName, promptName, _ := ms.parsePromptCommand(input)
+	client := ms.MCPClients[serverName]
+
+	var prompt *mcp.Prompt
+	for _, p := range ms.listServerPrompts(ctx) {
+		if p.server == serverName && p.prompt.Name == promptName {
+			prompt = &p.prompt
+			break
+		}
+	}
+	if prompt == nil {
+		return fmt.Errorf("server %s has no prompt named %s", serverName, promptName)
+	}
+
+	args := make(map[string]string)
+	for _, field := range strings.Fields(input)[1:] {
+		if name, value, ok := strings.Cut(field, "="); ok {
+			args[name] = value
+		}
+	}
+	if err := askPromptArguments(prompt, args); err != nil {
+		return err
+	}
+
+	req := mcp.GetPromptRequest{}
+	req.Params.Name = promptName
+	req.Params.Arguments = args
+	var result *mcp.GetPromptResult
+	var err error
+	callback(ctx, promptName, MODE_GET_PROMPT, func() {
+		getCtx, cancel := context.WithTimeout(ctx, promptTimeout)
+		defer cancel()
+		result, err = client.GetPrompt(getCtx, req)
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get prompt %s from %s: %w", promptName, serverName, err)
+	}
+
+	added := promptMessages(result.Messages)
+	if len(added) == 0 {
+		return fmt.Errorf("prompt %s from %s returned no messages", promptName, serverName)
+	}
+	*messages = append(*messages, added...)
+	log.Info("Added prompt", "server", serverName, "prompt", promptName, "messages", len(added))
+	return nil
+}
+
+// askPromptArguments fills in the arguments not given inline with a form.
+// Required arguments cannot be left empty.
+func askPromptArguments(prompt *mcp.Prompt, args map[string]string) error {
+	missing := false
+	for _, arg := range prompt.Arguments {
+		if _, ok := args[arg.Name]; !ok {
+			missing = true
+		}
+	}
+	if !missing {
+		return nil
+	}
+
+	values := make([]string, len(prompt.Arguments))
+	fields := make([]huh.Field, len(prompt.Arguments))
+	for i, arg := range prompt.Arguments {
+		values[i] = args[arg.Name]
+		title := arg.Name
+		if arg.Required {
+			title += " *"
+		}
+		input := huh.NewInput().
+			Title(title).
+			Description(arg.Description).
+			Value(&values[i])
+		if arg.Required {
+			name := arg.Name
+			input = input.Validate(func(s string) error {
+				if strings.TrimSpace(s) == "" {
+					return fmt.Errorf("%s is required", name)
+				}
+				return nil
+			})
+		}
+		fields[i] = input
+	}
+
+	err := huh.NewForm(huh.NewGroup(fields...)).
+		WithWidth(getTerminalWidth()).
+		WithTheme(huh.ThemeCharm()).
+		Run()
+	if errors.Is(err, huh.ErrUserAborted) {
+		return fmt.Errorf("prompt %s cancelled", prompt.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	for i, arg := range prompt.Arguments {
+		if values[i] != "" {
+			args[arg.Name] = values[i]
+		} else {
+			delete(args, arg.Name)
+		}
+	}
+	return nil
+}
+
+// promptMessages converts the messages of a prompt into history messages,
+// merging consecutive messages of the same role
+func promptMessages(promptMessages []mcp.PromptMessage) []history.HistoryMessage {
+	var messages []history.HistoryMessage
+	for _, pm := range promptMessages {
+		var block history.ContentBlock
+		switch c := pm.Content.(type) {
+		case mcp.TextContent:
+			block = history.ContentBlock{Type: "text", Text: c.Text}
+		case mcp.EmbeddedResource:
+			block = history.ResourceBlock(c.Resource)
+		case mcp.ImageContent:
+			block = history.ContentBlock{Type: "text", Text: "[image from the prompt is not shown]"}
+		default:
+			log.Debug("Skipping unsupported prompt content", "content", c)
+			continue
+		}
+
+		role := string(pm.Role)
+		if n := len(messages); n > 0 && messages[n-1].Role == role {
+			messages[n-1].Content = append(messages[n-1].Content, block)
+			continue
+		}
+		messages = append(messages, history.HistoryMessage{
+			Role:    role,
+			Content: []history.ContentBlock{block},
+		})
+	}
+	return messages
+}
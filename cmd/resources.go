@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh/spinner"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/list"
+	"github.com/charmbracelet/log"
+	mcpclient "github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcphost/pkg/history"
+)
+
+// resourceTimeout bounds listing or reading the resources of one server
+const resourceTimeout = 30 * time.Second
+
+// resourceRefPattern matches @server:uri references in a prompt
+var resourceRefPattern = regexp.MustCompile(`(^|\s)@([\w.-]+):(\S+)`)
+
+// capabilitiesClient is implemented by clients that remember what the
+// server announced during initialization
+type capabilitiesClient interface {
+	GetServerCapabilities() mcp.ServerCapabilities
+}
+
+// hasResources reports whether a server offers resources. Clients that do
+// not know are assumed to.
+func hasResources(client mcpclient.MCPClient) bool {
+	if c, ok := client.(capabilitiesClient); ok {
+		return c.GetServerCapabilities().Resources != nil
+	}
+	return true
+}
+
+// attachResources reads the resources referenced as @server:uri in prompt
+// and returns their contents as blocks for the user message. References to
+// names that are not servers are left alone, so e-mail addresses and the
+// like do not fail the prompt.
+func (ms *MCPSession) attachResources(ctx context.Context, prompt string) ([]history.ContentBlock, error) {
+	var blocks []history.ContentBlock
+	seen := make(map[string]bool)
+
+	for _, match := range resourceRefPattern.FindAllStringSubmatch(prompt, -1) {
+		serverName := match[2]
+		// Punctuation after a reference belongs to the sentence
+		uri := strings.TrimRight(match[3], ".,;:!?)\"'")
+
+		client, ok := ms.MCPClients[serverName]
+		if !ok || uri == "" || seen[serverName+":"+uri] {
+			continue
+		}
+		seen[serverName+":"+uri] = true
+
+		readCtx, cancel := context.WithTimeout(ctx, resourceTimeout)
+		req := mcp.ReadResourceRequest{}
+		req.Params.URI = uri
+		result, err := client.ReadResource(readCtx, req)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read resource @%s:%s: %w", serverName, uri, err)
+		}
+
+		log.Info("Attached resource", "server", serverName, "uri", uri, "contents", len(result.Contents))
+		for _, contents := range result.Contents {
+			blocks = append(blocks, history.ResourceBlock(contents))
+		}
+	}
+	return blocks, nil
+}
+
+func handleResourcesCommand(mcpClients map[string]mcpclient.MCPClient) {
+	width := getTerminalWidth()
+	contentWidth := width - 12
+
+	type serverResources struct {
+		resources []mcp.Resource
+		templates []mcp.ResourceTemplate
+		err       error
+	}
+	results := make(map[string]serverResources)
+
+	action := func() {
+		for serverName, mcpClient := range mcpClients {
+			if !hasResources(mcpClient) {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), resourceTimeout)
+
+			var result serverResources
+			// The client follows nextCursor until every page is read
+			resources, err := mcpClient.ListResources(ctx, mcp.ListResourcesRequest{})
+			if err != nil {
+				result.err = err
+			} else {
+				result.resources = resources.Resources
+			}
+			if result.err == nil {
+				templates, err := mcpClient.ListResourceTemplates(ctx, mcp.ListResourceTemplatesRequest{})
+				// Templates are optional, not every server answers this
+				if err == nil {
+					result.templates = templates.ResourceTemplates
+				} else {
+					log.Debug("Failed to list resource templates", "server", serverName, "error", err)
+				}
+			}
+			cancel()
+			results[serverName] = result
+		}
+	}
+	_ = spinner.New().
+		Title("Fetching resources from all servers...").
+		Action(action).
+		Run()
+
+	if len(results) == 0 {
+		fmt.Print("\n" + contentStyle.Render("No server offers resources.\n") + "\n\n")
+		return
+	}
+
+	serverNames := make([]string, 0, len(results))
+	for name := range results {
+		serverNames = append(serverNames, name)
+	}
+	sort.Strings(serverNames)
+
+	descStyle := lipgloss.NewStyle().
+		Foreground(tokyoFg).
+		Width(contentWidth).
+		Align(lipgloss.Left)
+
+	l := list.New().
+		EnumeratorStyle(lipgloss.NewStyle().Foreground(tokyoPurple).MarginRight(1))
+
+	for _, serverName := range serverNames {
+		result := results[serverName]
+		if result.err != nil {
+			fmt.Printf(
+				"\n%s\n",
+				errorStyle.Render(fmt.Sprintf("Error fetching resources from %s: %v", serverName, result.err)),
+			)
+			continue
+		}
+
+		serverList := list.New().
+			EnumeratorStyle(lipgloss.NewStyle().Foreground(tokyoCyan).MarginRight(1))
+
+		if len(result.resources) == 0 && len(result.templates) == 0 {
+			serverList.Item("No resources available")
+		}
+		for _, resource := range result.resources {
+			details := list.New().
+				EnumeratorStyle(lipgloss.NewStyle().Foreground(tokyoGreen).MarginRight(1)).
+				Item(descStyle.Render(fmt.Sprintf("@%s:%s", serverName, resource.URI)))
+			if resource.Description != "" {
+				details.Item(descStyle.Render(resource.Description))
+			}
+			if resource.MIMEType != "" {
+				details.Item(descStyle.Render(resource.MIMEType))
+			}
+			serverList.Item(toolNameStyle.Render(resource.Name)).Item(details)
+		}
+		for _, template := range result.templates {
+			uriTemplate := ""
+			if template.URITemplate != nil {
+				uriTemplate = template.URITemplate.Raw()
+			}
+			details := list.New().
+				EnumeratorStyle(lipgloss.NewStyle().Foreground(tokyoGreen).MarginRight(1)).
+				Item(descStyle.Render(fmt.Sprintf("@%s:%s (template)", serverName, uriTemplate)))
+			if template.Description != "" {
+				details.Item(descStyle.Render(template.Description))
+			}
+			serverList.Item(toolNameStyle.Render(template.Name)).Item(details)
+		}
+
+		l.Item(serverName).Item(serverList)
+	}
+
+	containerStyle := lipgloss.NewStyle().
+		Margin(2).
+		Width(width)
+
+	fmt.Print("\n" + containerStyle.Render(l.String()) + "\n")
+	fmt.Print(contentStyle.Render("Mention @server:uri in a prompt to attach a resource.") + "\n\n")
+}
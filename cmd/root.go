@@ -6,12 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/glamour/styles"
 	"github.com/charmbracelet/huh"
-	"github.com/charmbracelet/huh/spinner"
 	"github.com/charmbracelet/log"
 
 	"github.com/charmbracelet/glamour"
@@ -30,6 +30,19 @@ var (
 	configFile       string
 	systemPromptFile string
 	messageWindow    int
+	contextWindow    int
+	reserveTokens    int
+	compactThreshold float64
+	compactModel     string
+	transcriptDir    string
+	outputSchemaFile string
+	promptFlag       string
+	toolChoiceFlag   string
+	toolApproval     string
+	traceHTTPDir     string
+	traceTruncate    bool
+	maxContinuations int
+	rootFlags        []string
 	modelFlag        string // New flag for model selection
 	openaiBaseURL    string // Base URL for OpenAI API
 	anthropicBaseURL string // Base URL for Anthropic API
@@ -80,7 +93,29 @@ func init() {
 	rootCmd.PersistentFlags().
 		StringVar(&systemPromptFile, "system-prompt", "", "system prompt json file")
 	rootCmd.PersistentFlags().
-		IntVar(&messageWindow, "message-window", 10, "number of messages to keep in context")
+		IntVar(&messageWindow, "message-window", 0, "maximum number of messages to keep in context (0 keeps as many as fit the context window)")
+	rootCmd.PersistentFlags().
+		IntVar(&contextWindow, "context-window", 0, "context window size in tokens (defaults to the known limit for the model)")
+	rootCmd.PersistentFlags().
+		IntVar(&reserveTokens, "reserve-tokens", 4096, "tokens of the context window to reserve for the response")
+	rootCmd.PersistentFlags().
+		Float64Var(&compactThreshold, "compact-threshold", 0.8, "share of the context window at which older messages are summarized (0 disables)")
+	rootCmd.PersistentFlags().
+		StringVar(&compactModel, "compact-model", "", "model used to summarize the conversation (defaults to --model)")
+	rootCmd.PersistentFlags().
+		StringVar(&transcriptDir, "transcript-dir", "", "directory to save the full conversation to before it is compacted")
+	rootCmd.PersistentFlags().
+		StringVar(&outputSchemaFile, "output-schema", "", "JSON Schema file the final answer must match")
+	rootCmd.PersistentFlags().
+		StringVarP(&promptFlag, "prompt", "p", "", "run a single prompt non-interactively and print the answer")
+	rootCmd.PersistentFlags().
+		StringVar(&toolChoiceFlag, "tool-choice", "auto", "tool use for each prompt: auto, none, required, or a server__tool name")
+	rootCmd.PersistentFlags().
+		StringVar(&toolApproval, "tool-approval", "", "which tool calls to approve first: always, writes (tools not marked read-only) or never (default)")
+	rootCmd.PersistentFlags().
+		IntVar(&maxContinuations, "max-continuations", 3, "how many times a reply cut off at the output token limit is continued")
+	rootCmd.PersistentFlags().
+		StringArrayVar(&rootFlags, "root", nil, "directory offered to MCP servers as a root besides the working directory (repeatable)")
 	rootCmd.PersistentFlags().
 		StringVarP(&modelFlag, "model", "m", "anthropic:claude-3-5-sonnet-latest",
 			"model to use (format: provider:model, e.g. anthropic:claude-3-5-sonnet-latest or ollama:qwen2.5:3b)")
@@ -88,6 +123,10 @@ func init() {
 	// Add debug flag
 	rootCmd.PersistentFlags().
 		BoolVar(&debugMode, "debug", false, "enable debug logging")
+	rootCmd.PersistentFlags().
+		StringVar(&traceHTTPDir, "trace-http", "", "directory to write redacted provider HTTP requests and responses to")
+	rootCmd.PersistentFlags().
+		BoolVar(&traceTruncate, "trace-truncate-images", false, "shorten base64 image data in --trace-http request files")
 
 	flags := rootCmd.PersistentFlags()
 	flags.StringVar(&openaiBaseURL, "openai-url", "", "base URL for OpenAI API (defaults to api.openai.com)")
@@ -97,8 +136,26 @@ func init() {
 	flags.StringVar(&googleAPIKey, "google-api-key", "", "Google (Gemini) API key")
 }
 
+// apiKeySecrets returns every API key that might end up in a request, so that
+// HTTP traces can redact them
+func apiKeySecrets() []string {
+	return []string{
+		anthropicAPIKey,
+		openaiAPIKey,
+		googleAPIKey,
+		os.Getenv("ANTHROPIC_API_KEY"),
+		os.Getenv("OPENAI_API_KEY"),
+		os.Getenv("GOOGLE_API_KEY"),
+		os.Getenv("GEMINI_API_KEY"),
+	}
+}
+
 // Add new function to create provider
-func createProvider(ctx context.Context, modelString, systemPrompt string) (llm.Provider, error) {
+func createProvider(
+	ctx context.Context,
+	modelString, systemPrompt string,
+	config *MCPConfig,
+) (llm.Provider, error) {
 	parts := strings.SplitN(modelString, ":", 2)
 	if len(parts) < 2 {
 		return nil, fmt.Errorf(
@@ -110,6 +167,24 @@ func createProvider(ctx context.Context, modelString, systemPrompt string) (llm.
 	provider := parts[0]
 	model := parts[1]
 
+	providerConfig := config.providerConfig(provider)
+	httpClient, err := config.httpClient(providerConfig.HTTP)
+	if err != nil {
+		return nil, fmt.Errorf("invalid HTTP settings for %s: %v", provider, err)
+	}
+	if config != nil && config.tracer != nil {
+		httpClient = config.tracer.Client(httpClient)
+	}
+	// Added outside the tracer so that traces show the final request
+	if headers := config.providerHeaders(provider); !headers.IsZero() {
+		if config != nil {
+			headers.HideFrom(config.tracer)
+		}
+		httpClient = headers.Client(httpClient)
+	}
+	// A headers command can provide the credentials instead of an API key
+	requireAPIKey := len(providerConfig.HeadersCommand) == 0
+
 	switch provider {
 	case "anthropic":
 		apiKey := anthropicAPIKey
@@ -117,15 +192,15 @@ func createProvider(ctx context.Context, modelString, systemPrompt string) (llm.
 			apiKey = os.Getenv("ANTHROPIC_API_KEY")
 		}
 
-		if apiKey == "" {
+		if apiKey == "" && requireAPIKey {
 			return nil, fmt.Errorf(
 				"Anthropic API key not provided. Use --anthropic-api-key flag or ANTHROPIC_API_KEY environment variable",
 			)
 		}
-		return anthropic.NewProvider(apiKey, anthropicBaseURL, model, systemPrompt), nil
+		return anthropic.NewProvider(apiKey, anthropicBaseURL, model, systemPrompt, httpClient), nil
 
 	case "ollama":
-		return ollama.NewProvider(model, systemPrompt)
+		return ollama.NewProvider(model, systemPrompt, config.ollamaConfig(), httpClient)
 
 	case "openai":
 		apiKey := openaiAPIKey
@@ -133,12 +208,12 @@ func createProvider(ctx context.Context, modelString, systemPrompt string) (llm.
 			apiKey = os.Getenv("OPENAI_API_KEY")
 		}
 
-		if apiKey == "" {
+		if apiKey == "" && requireAPIKey {
 			return nil, fmt.Errorf(
 				"OpenAI API key not provided. Use --openai-api-key flag or OPENAI_API_KEY environment variable",
 			)
 		}
-		return openai.NewProvider(apiKey, openaiBaseURL, model, systemPrompt), nil
+		return openai.NewProvider(apiKey, openaiBaseURL, model, systemPrompt, httpClient), nil
 
 	case "google":
 		apiKey := googleAPIKey
@@ -149,20 +224,60 @@ func createProvider(ctx context.Context, modelString, systemPrompt string) (llm.
 			// The project structure is provider specific, but Google calls this GEMINI_API_KEY in e.g. AI Studio. Support both.
 			apiKey = os.Getenv("GEMINI_API_KEY")
 		}
-		return google.NewProvider(ctx, apiKey, model, systemPrompt)
+		return google.NewProvider(ctx, apiKey, model, systemPrompt, httpClient)
 
 	default:
 		return nil, fmt.Errorf("unsupported provider: %s", provider)
 	}
 }
 
-func pruneMessages(messages []history.HistoryMessage) []history.HistoryMessage {
-	if len(messages) <= messageWindow {
-		return messages
+// pruneMessages trims the oldest messages until the history fits within the
+// token budget. Whole exchanges are dropped first; if only the current exchange
+// is left, its oldest tool rounds are dropped while the user prompt is kept.
+// Tool calls are always dropped together with their results.
+func pruneMessages(
+	messages []history.HistoryMessage,
+	budget int,
+	estimate func(history.HistoryMessage) int,
+) []history.HistoryMessage {
+	if messageWindow > 0 && len(messages) > messageWindow {
+		messages = messages[len(messages)-messageWindow:]
 	}
 
-	// Keep only the most recent messages based on window size
-	messages = messages[len(messages)-messageWindow:]
+	total := 0
+	for _, msg := range messages {
+		total += estimate(msg)
+	}
+
+	for total > budget {
+		// Find the start of the next exchange
+		n := 1
+		for n < len(messages) && messages[n].Role != "user" {
+			n++
+		}
+
+		start := 0
+		if n >= len(messages) {
+			// Only the current exchange is left, drop its oldest assistant
+			// message together with the tool results that follow it
+			start = 1
+			n = 2
+			for n < len(messages) && messages[n].Role == "tool" {
+				n++
+			}
+			if n >= len(messages) {
+				log.Warn("Conversation exceeds the context budget and cannot be pruned further",
+					"estimated_tokens", total,
+					"budget", budget)
+				break
+			}
+		}
+
+		for _, msg := range messages[start:n] {
+			total -= estimate(msg)
+		}
+		messages = append(messages[:start:start], messages[n:]...)
+	}
 
 	// Handle messages
 	toolUseIds := make(map[string]bool)
@@ -250,6 +365,19 @@ func runMCPHost(ctx context.Context) error {
 		ConfigFile:       configFile,
 		SystemPromptFile: systemPromptFile,
 		ModelFlag:        modelFlag,
+		ContextWindow:    contextWindow,
+		ReserveTokens:    reserveTokens,
+		CompactThreshold: compactThreshold,
+		CompactModel:     compactModel,
+		TranscriptDir:    transcriptDir,
+		OutputSchemaFile: outputSchemaFile,
+		ToolChoice:       toolChoiceFlag,
+		InTerminal:       promptFlag == "" && term.IsTerminal(int(os.Stdin.Fd())),
+		TraceHTTPDir:     traceHTTPDir,
+		TraceTruncate:    traceTruncate,
+		MaxContinuations: maxContinuations,
+		Roots:            rootFlags,
+		ToolApproval:     toolApproval,
 	})
 	if err != nil {
 		return fmt.Errorf("error initializing session: %v", err)
@@ -267,6 +395,10 @@ func runMCPHost(ctx context.Context) error {
 		return fmt.Errorf("error creating provider: %v", err)
 	}
 
+	if promptFlag != "" {
+		return runNonInteractive(ctx, ms, promptFlag)
+	}
+
 	if err := updateRenderer(); err != nil {
 		return fmt.Errorf("error initializing renderer: %v", err)
 	}
@@ -297,23 +429,25 @@ func runMCPHost(ctx context.Context) error {
 			continue
 		}
 
-		// Handle slash commands
-		handled, err := handleSlashCommand(
-			prompt,
-			ms.Config,
-			ms.MCPClients,
-			messages,
-		)
-		if err != nil {
-			return err
-		}
-		if handled {
-			continue
+		_, _, serverPrompt := ms.parsePromptCommand(prompt)
+		if !serverPrompt {
+			// Handle slash commands
+			handled, err := handleSlashCommand(
+				ctx,
+				prompt,
+				ms,
+				&messages,
+			)
+			if err != nil {
+				return err
+			}
+			if handled {
+				continue
+			}
 		}
 
-		if len(messages) > 0 {
-			messages = pruneMessages(messages)
-		}
+		// Esc or Ctrl+C while a spinner is shown cancels only this turn
+		turnCtx, cancelTurn := context.WithCancel(ctx)
 
 		callback := func(
 			ctx context.Context,
@@ -330,7 +464,7 @@ func runMCPHost(ctx context.Context) error {
 			case MODE_CREATE_MESSAGE:
 				if action != nil {
 					// If an action is provided, run it
-					err = spinner.New().Title("Thinking...").Action(action).Run()
+					return runCancellable("Thinking...", cancelTurn, action)
 				}
 			case MODE_ASSISTANT_MESSAGE:
 				// Handle the message response
@@ -351,12 +485,20 @@ func runMCPHost(ctx context.Context) error {
 				if text == "" {
 					return nil // Skip empty tool messages
 				}
-				_ = spinner.New().
-					Title(fmt.Sprintf("Running tool %s...", text)).
-					Action(action).
-					Run()
+				return runCancellable(fmt.Sprintf("Running tool %s...", text), cancelTurn, action)
 			case MODE_ERROR:
 				fmt.Printf("\n%s\n", errorStyle.Render(text))
+			case MODE_STRUCTURED_OUTPUT:
+				str, err := renderer.Render("```json\n" + text + "\n```\n")
+				if err != nil {
+					fmt.Println(text)
+				} else {
+					fmt.Print(str)
+				}
+			case MODE_COMPACT:
+				return runCancellable("Compacting conversation...", cancelTurn, action)
+			case MODE_GET_PROMPT:
+				return runCancellable(fmt.Sprintf("Getting prompt %s...", text), cancelTurn, action)
 
 			default:
 				if action != nil {
@@ -367,14 +509,75 @@ func runMCPHost(ctx context.Context) error {
 			}
 			return nil
 		}
-		err = ms.RunPrompt(ctx, prompt, &messages, callback)
+		if serverPrompt {
+			// Server prompts add their messages, then the model answers them
+			fmt.Printf("\n%s\n", promptStyle.Render("You: "+prompt))
+			err = ms.applyServerPrompt(turnCtx, prompt, &messages, callback)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				cancelTurn()
+				fmt.Printf("\n%s\n\n", errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+				continue
+			}
+			if err == nil {
+				err = ms.runTurn(turnCtx, "", true, &messages, callback)
+			}
+		} else {
+			err = ms.RunPrompt(turnCtx, prompt, &messages, callback)
+		}
+		cancelTurn()
 		fmt.Println() // Add spacing
+		if errors.Is(err, context.Canceled) && ctx.Err() == nil {
+			fmt.Printf("%s\n\n", errorStyle.Render("Cancelled."))
+			continue
+		}
 		if err != nil {
 			return err
 		}
 	}
 }
 
+// runNonInteractive runs a single prompt without the interactive UI. Only the
+// answer is written to stdout so that it can be consumed by scripts; with an
+// output schema that is just the JSON document.
+func runNonInteractive(ctx context.Context, ms *MCPSession, prompt string) error {
+	// Let servers know about abandoned tool calls when interrupted
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	messages := make([]history.HistoryMessage, 0)
+
+	callback := func(
+		ctx context.Context,
+		text string,
+		mode int,
+		action func(),
+	) error {
+		switch mode {
+		case MODE_USER_PROMPT:
+		case MODE_ASSISTANT_MESSAGE:
+			if text == "" {
+				return nil
+			}
+			if ms.OutputSchema != nil {
+				fmt.Fprintln(os.Stderr, text)
+			} else {
+				fmt.Println(text)
+			}
+		case MODE_STRUCTURED_OUTPUT:
+			fmt.Println(text)
+		case MODE_ERROR:
+			fmt.Fprintln(os.Stderr, text)
+		default:
+			if action != nil {
+				action()
+			}
+		}
+		return nil
+	}
+
+	return ms.RunPrompt(ctx, prompt, &messages, callback)
+}
+
 // loadSystemPrompt loads the system prompt from a JSON file
 func loadSystemPrompt(filePath string) (string, error) {
 	if filePath == "" {
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mark3labs/mcphost/pkg/history"
+)
+
+func userMessage(text string) history.HistoryMessage {
+	return history.HistoryMessage{
+		Role:    "user",
+		Content: []history.ContentBlock{{Type: "text", Text: text}},
+	}
+}
+
+func assistantMessage(text string) history.HistoryMessage {
+	return history.HistoryMessage{
+		Role:    "assistant",
+		Content: []history.ContentBlock{{Type: "text", Text: text}},
+	}
+}
+
+func toolUseMessage(id string) history.HistoryMessage {
+	return history.HistoryMessage{
+		Role:    "assistant",
+		Content: []history.ContentBlock{{Type: "tool_use", ID: id, Name: "server__tool", Input: []byte(`{}`)}},
+	}
+}
+
+func toolResultMessage(id string) history.HistoryMessage {
+	return history.HistoryMessage{
+		Role:    "tool",
+		Content: []history.ContentBlock{{Type: "tool_result", ToolUseID: id, Text: "result " + id}},
+	}
+}
+
+// describe reduces messages to role:text or role:block-type:id for comparison
+func describe(messages []history.HistoryMessage) []string {
+	var out []string
+	for _, msg := range messages {
+		for _, block := range msg.Content {
+			switch block.Type {
+			case "text":
+				out = append(out, msg.Role+":"+block.Text)
+			case "tool_use":
+				out = append(out, msg.Role+":tool_use:"+block.ID)
+			case "tool_result":
+				out = append(out, msg.Role+":tool_result:"+block.ToolUseID)
+			}
+		}
+	}
+	return out
+}
+
+func TestPruneMessages(t *testing.T) {
+	// Every message costs 10 tokens
+	estimate := func(history.HistoryMessage) int { return 10 }
+
+	tests := []struct {
+		name     string
+		window   int
+		budget   int
+		messages []history.HistoryMessage
+		want     []string
+	}{
+		{
+			name:   "fits the budget",
+			budget: 100,
+			messages: []history.HistoryMessage{
+				userMessage("one"), assistantMessage("answer one"),
+				userMessage("two"),
+			},
+			want: []string{"user:one", "assistant:answer one", "user:two"},
+		},
+		{
+			name:   "drops the oldest exchanges",
+			budget: 30,
+			messages: []history.HistoryMessage{
+				userMessage("one"), assistantMessage("answer one"),
+				userMessage("two"), assistantMessage("answer two"),
+				userMessage("three"),
+			},
+			want: []string{"user:two", "assistant:answer two", "user:three"},
+		},
+		{
+			name:   "drops an exchange together with its tool rounds",
+			budget: 20,
+			messages: []history.HistoryMessage{
+				userMessage("one"), toolUseMessage("a"), toolResultMessage("a"), assistantMessage("answer one"),
+				userMessage("two"), assistantMessage("answer two"),
+			},
+			want: []string{"user:two", "assistant:answer two"},
+		},
+		{
+			name:   "current exchange alone exceeds the budget",
+			budget: 50,
+			messages: []history.HistoryMessage{
+				userMessage("one"),
+				toolUseMessage("a"), toolResultMessage("a"),
+				toolUseMessage("b"), toolResultMessage("b"),
+				toolUseMessage("c"), toolResultMessage("c"),
+			},
+			want: []string{
+				"user:one",
+				"assistant:tool_use:b", "tool:tool_result:b",
+				"assistant:tool_use:c", "tool:tool_result:c",
+			},
+		},
+		{
+			// The model is answering the latest tool round, so it stays
+			name:   "keeps the prompt and the latest tool round over budget",
+			budget: 10,
+			messages: []history.HistoryMessage{
+				userMessage("one"), toolUseMessage("a"), toolResultMessage("a"),
+			},
+			want: []string{"user:one", "assistant:tool_use:a", "tool:tool_result:a"},
+		},
+		{
+			name:   "message window cuts between a tool call and its result",
+			window: 3,
+			budget: 100,
+			messages: []history.HistoryMessage{
+				userMessage("one"), toolUseMessage("a"), toolResultMessage("a"), assistantMessage("answer one"),
+				userMessage("two"),
+			},
+			want: []string{"assistant:answer one", "user:two"},
+		},
+		{
+			name:   "message window cuts between a tool result and its call",
+			window: 3,
+			budget: 100,
+			messages: []history.HistoryMessage{
+				userMessage("zero"), userMessage("one"), toolUseMessage("a"), toolResultMessage("a"),
+			},
+			want: []string{"user:one", "assistant:tool_use:a", "tool:tool_result:a"},
+		},
+		{
+			name:   "removes a tool call whose result is missing",
+			budget: 100,
+			messages: []history.HistoryMessage{
+				toolResultMessage("x"),
+				userMessage("one"), toolUseMessage("y"),
+			},
+			want: []string{"user:one"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := messageWindow
+			messageWindow = tt.window
+			t.Cleanup(func() { messageWindow = saved })
+
+			got := describe(pruneMessages(tt.messages, tt.budget, estimate))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pruneMessages() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
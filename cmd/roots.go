@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// rootsNotifyTimeout bounds telling one server that the roots changed
+const rootsNotifyTimeout = 5 * time.Second
+
+// rootsHandler answers roots/list requests with the session's roots
+type rootsHandler struct {
+	ms *MCPSession
+}
+
+func (h *rootsHandler) ListRoots(ctx context.Context, request mcp.ListRootsRequest) (*mcp.ListRootsResult, error) {
+	var roots []mcp.Root
+	for _, dir := range h.ms.Roots() {
+		roots = append(roots, mcp.Root{URI: fileURI(dir), Name: filepath.Base(dir)})
+	}
+	return &mcp.ListRootsResult{Roots: roots}, nil
+}
+
+// rootsChangedNotifier is implemented by clients that can tell their server
+// the roots changed
+type rootsChangedNotifier interface {
+	RootListChanges(ctx context.Context) error
+}
+
+// fileURI turns an absolute path into a file:// URI
+func fileURI(path string) string {
+	path = filepath.ToSlash(path)
+	// Windows paths such as C:/dir need a leading slash
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// resolveRoot makes a root directory absolute like absRoot, warning when it
+// is not a directory
+func resolveRoot(dir string) (string, error) {
+	abs, err := absRoot(dir)
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+		log.Warn("Root is not a directory", "root", abs)
+	}
+	return abs, nil
+}
+
+// absRoot makes a root directory absolute, expanding a leading ~
+func absRoot(dir string) (string, error) {
+	if dir == "~" || strings.HasPrefix(dir, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, dir[1:])
+	}
+	return filepath.Abs(dir)
+}
+
+// initRoots sets the session's roots to the working directory followed by
+// the roots from the config and the --root flags
+func (ms *MCPSession) initRoots(extra []string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("error getting working directory: %v", err)
+	}
+
+	roots := []string{cwd}
+	for _, dir := range append(append([]string(nil), ms.Config.Roots...), extra...) {
+		abs, err := resolveRoot(dir)
+		if err != nil {
+			return fmt.Errorf("invalid root %s: %v", dir, err)
+		}
+		if !slices.Contains(roots, abs) {
+			roots = append(roots, abs)
+		}
+	}
+
+	ms.rootsMu.Lock()
+	ms.roots = roots
+	ms.rootsMu.Unlock()
+	return nil
+}
+
+// Roots returns the directories servers are told to work in
+func (ms *MCPSession) Roots() []string {
+	ms.rootsMu.RLock()
+	defer ms.rootsMu.RUnlock()
+	return slices.Clone(ms.roots)
+}
+
+// AddRoot adds a directory to the roots and notifies the servers
+func (ms *MCPSession) AddRoot(ctx context.Context, dir string) (string, error) {
+	abs, err := resolveRoot(dir)
+	if err != nil {
+		return "", err
+	}
+
+	ms.rootsMu.Lock()
+	if slices.Contains(ms.roots, abs) {
+		ms.rootsMu.Unlock()
+		return "", fmt.Errorf("%s is already a root", abs)
+	}
+	ms.roots = append(ms.roots, abs)
+	ms.rootsMu.Unlock()
+
+	ms.notifyRootsChanged(ctx)
+	return abs, nil
+}
+
+// RemoveRoot removes a directory from the roots and notifies the servers
+func (ms *MCPSession) RemoveRoot(ctx context.Context, dir string) (string, error) {
+	abs, err := absRoot(dir)
+	if err != nil {
+		return "", err
+	}
+
+	ms.rootsMu.Lock()
+	i := slices.Index(ms.roots, abs)
+	if i < 0 {
+		ms.rootsMu.Unlock()
+		return "", fmt.Errorf("%s is not a root", abs)
+	}
+	ms.roots = slices.Delete(ms.roots, i, i+1)
+	ms.rootsMu.Unlock()
+
+	ms.notifyRootsChanged(ctx)
+	return abs, nil
+}
+
+// notifyRootsChanged sends notifications/roots/list_changed to every
+// server, which then asks for the new roots
+func (ms *MCPSession) notifyRootsChanged(ctx context.Context) {
+	for name, client := range ms.MCPClients {
+		notifier, ok := client.(rootsChangedNotifier)
+		// Servers that are down get the roots when they are back
+		if !ok || unavailable(client) != nil {
+			continue
+		}
+		notifyCtx, cancel := context.WithTimeout(ctx, rootsNotifyTimeout)
+		if err := notifier.RootListChanges(notifyCtx); err != nil {
+			log.Warn("Failed to notify server of new roots", "server", name, "error", err)
+		}
+		cancel()
+	}
+}
+
+func handleRootsCommand(ctx context.Context, ms *MCPSession, args []string) {
+	if len(args) == 0 {
+		var b strings.Builder
+		b.WriteString("Roots:\n")
+		for _, dir := range ms.Roots() {
+			b.WriteString("  " + fileURI(dir) + "\n")
+		}
+		fmt.Printf("\n%s\n", promptStyle.Render(b.String()))
+		return
+	}
+
+	if len(args) < 2 || (args[0] != "add" && args[0] != "remove") {
+		fmt.Printf("\n%s\n\n", errorStyle.Render("Usage: /roots [add|remove <dir>]"))
+		return
+	}
+
+	dir := strings.Join(args[1:], " ")
+	var err error
+	if args[0] == "add" {
+		dir, err = ms.AddRoot(ctx, dir)
+	} else {
+		dir, err = ms.RemoveRoot(ctx, dir)
+	}
+	if err != nil {
+		fmt.Printf("\n%s\n\n", errorStyle.Render(fmt.Sprintf("Error updating roots: %v", err)))
+		return
+	}
+
+	verb := "Added"
+	if args[0] == "remove" {
+		verb = "Removed"
+	}
+	fmt.Printf("\n%s\n\n", promptStyle.Render(fmt.Sprintf("%s root %s", verb, dir)))
+}
@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/log"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcphost/pkg/history"
+	"github.com/mark3labs/mcphost/pkg/llm"
+)
+
+// samplingPreviewLength limits how much of each message the approval
+// prompt shows
+const samplingPreviewLength = 500
+
+// samplingHandler answers a server's sampling/createMessage requests with
+// one of our models, after the user has approved them
+type samplingHandler struct {
+	ms     *MCPSession
+	server string
+
+	// alwaysAllow is set when the user allows all of the server's requests
+	// for the rest of the session
+	mu          sync.Mutex
+	alwaysAllow bool
+}
+
+func (h *samplingHandler) CreateMessage(
+	ctx context.Context,
+	request mcp.CreateMessageRequest,
+) (*mcp.CreateMessageResult, error) {
+	params := request.CreateMessageParams
+	model := h.ms.samplingModel(params.ModelPreferences)
+
+	maxTokens := params.MaxTokens
+	if cfg := h.ms.Config.Sampling; cfg != nil && cfg.MaxTokens > 0 && (maxTokens <= 0 || maxTokens > cfg.MaxTokens) {
+		maxTokens = cfg.MaxTokens
+	}
+
+	if err := h.approve(model, maxTokens, params); err != nil {
+		log.Info("Sampling request declined", "server", h.server, "reason", err)
+		return nil, err
+	}
+
+	provider, err := h.ms.samplingProvider(ctx, model, params.SystemPrompt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider for %s: %w", model, err)
+	}
+
+	var opts []llm.RequestOption
+	if maxTokens > 0 {
+		opts = append(opts, llm.WithMaxTokens(maxTokens))
+	}
+	log.Info("Running sampling request", "server", h.server, "model", model, "max_tokens", maxTokens)
+	message, err := provider.CreateMessage(ctx, "", samplingMessages(params.Messages), nil, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("sampling with %s failed: %w", model, err)
+	}
+
+	stopReason := "endTurn"
+	if llm.IsTruncated(message) {
+		stopReason = "maxTokens"
+	}
+	_, modelName, _ := strings.Cut(model, ":")
+	return &mcp.CreateMessageResult{
+		SamplingMessage: mcp.SamplingMessage{
+			Role:    mcp.RoleAssistant,
+			Content: mcp.NewTextContent(message.GetContent()),
+		},
+		Model:      modelName,
+		StopReason: stopReason,
+	}, nil
+}
+
+// approve asks the user whether a request may run, unless the server's
+// requests are approved automatically. Without a terminal nobody can
+// approve, so requests are declined.
+func (h *samplingHandler) approve(model string, maxTokens int, params mcp.CreateMessageParams) error {
+	if sampling := h.ms.serverOptions(h.server).Sampling; sampling != nil && sampling.AutoApprove {
+		return nil
+	}
+	h.mu.Lock()
+	alwaysAllow := h.alwaysAllow
+	h.mu.Unlock()
+	if alwaysAllow {
+		return nil
+	}
+	if !h.ms.InTerminal {
+		return fmt.Errorf("sampling request declined: it needs approval and mcphost is not interactive")
+	}
+
+	const (
+		allowOnce   = "once"
+		allowAlways = "always"
+		deny        = "deny"
+	)
+	choice := deny
+	err := askUser(func() error {
+		return huh.NewForm(huh.NewGroup(
+			huh.NewNote().
+				Title(fmt.Sprintf("%s wants to run the model", h.server)).
+				Description(describeSamplingRequest(model, maxTokens, params)),
+			huh.NewSelect[string]().
+				Title("Allow this request?").
+				Options(
+					huh.NewOption("Allow once", allowOnce),
+					huh.NewOption(fmt.Sprintf("Allow all requests from %s this session", h.server), allowAlways),
+					huh.NewOption("Deny", deny),
+				).
+				Value(&choice),
+		)).WithWidth(getTerminalWidth()).
+			WithTheme(huh.ThemeCharm()).
+			Run()
+	})
+	if errors.Is(err, huh.ErrUserAborted) {
+		return fmt.Errorf("sampling request declined by the user")
+	}
+	if err != nil {
+		return err
+	}
+
+	switch choice {
+	case allowAlways:
+		h.mu.Lock()
+		h.alwaysAllow = true
+		h.mu.Unlock()
+		return nil
+	case allowOnce:
+		return nil
+	}
+	return fmt.Errorf("sampling request declined by the user")
+}
+
+// describeSamplingRequest summarizes a request for the approval prompt
+func describeSamplingRequest(model string, maxTokens int, params mcp.CreateMessageParams) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Model: %s\n", model)
+	if maxTokens > 0 {
+		fmt.Fprintf(&b, "Max tokens: %d\n", maxTokens)
+	}
+	if params.SystemPrompt != "" {
+		fmt.Fprintf(&b, "System prompt: %s\n", truncateText(params.SystemPrompt, samplingPreviewLength))
+	}
+	for _, msg := range samplingMessages(params.Messages) {
+		fmt.Fprintf(&b, "\n%s: %s\n", msg.GetRole(), truncateText(msg.GetContent(), samplingPreviewLength))
+	}
+	return b.String()
+}
+
+func truncateText(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit]) + "..."
+}
+
+// samplingProviderKey identifies a provider created for sampling requests.
+// Providers take the system prompt when they are created, so it is part of
+// the key.
+type samplingProviderKey struct {
+	model        string
+	systemPrompt string
+}
+
+// samplingProvider returns the provider for a sampling request, creating it
+// the first time a model is used with a system prompt. Reusing providers
+// keeps their cached capabilities, which cost Ollama a request each time.
+func (ms *MCPSession) samplingProvider(ctx context.Context, model, systemPrompt string) (llm.Provider, error) {
+	ms.samplingMu.Lock()
+	defer ms.samplingMu.Unlock()
+	key := samplingProviderKey{model: model, systemPrompt: systemPrompt}
+	if provider, ok := ms.samplingProviders[key]; ok {
+		return provider, nil
+	}
+	// The provider outlives this request, so it must not be tied to it
+	provider, err := createProvider(context.WithoutCancel(ctx), model, systemPrompt, ms.Config)
+	if err != nil {
+		return nil, err
+	}
+	if ms.samplingProviders == nil {
+		ms.samplingProviders = make(map[samplingProviderKey]llm.Provider)
+	}
+	ms.samplingProviders[key] = provider
+	return provider, nil
+}
+
+// samplingModel picks the provider:model for a request. The first model
+// hint contained in one of the configured sampling models selects it,
+// otherwise the configured sampling model or the session's model is used.
+// The cost, speed and intelligence priorities are not used, since nothing
+// is known about the configured models to rank them by.
+func (ms *MCPSession) samplingModel(prefs *mcp.ModelPreferences) string {
+	cfg := ms.Config.Sampling
+	if cfg == nil {
+		return ms.Model
+	}
+	if prefs != nil {
+		for _, hint := range prefs.Hints {
+			if hint.Name == "" {
+				continue
+			}
+			for _, model := range cfg.Models {
+				if strings.Contains(model, hint.Name) {
+					return model
+				}
+			}
+		}
+	}
+	if cfg.Model != "" {
+		return cfg.Model
+	}
+	return ms.Model
+}
+
+// samplingMessages converts the messages of a sampling request
+func samplingMessages(messages []mcp.SamplingMessage) []llm.Message {
+	converted := make([]llm.Message, 0, len(messages))
+	for _, msg := range messages {
+		var text string
+		switch c := msg.Content.(type) {
+		case mcp.TextContent:
+			text = c.Text
+		case mcp.ImageContent:
+			text = "[image from the server is not shown]"
+		case mcp.AudioContent:
+			text = "[audio from the server is not shown]"
+		default:
+			log.Debug("Skipping unsupported sampling content", "content", c)
+			continue
+		}
+		converted = append(converted, &history.HistoryMessage{
+			Role:    string(msg.Role),
+			Content: []history.ContentBlock{{Type: "text", Text: text}},
+		})
+	}
+	return converted
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"path/filepath"
@@ -15,16 +16,13 @@ import (
 	mcpclient "github.com/mark3labs/mcp-go/client"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcphost/pkg/history"
+	"github.com/mark3labs/mcphost/pkg/httpclient"
 	"github.com/mark3labs/mcphost/pkg/llm"
-	"github.com/mark3labs/mcphost/pkg/llm/anthropic"
-	"github.com/mark3labs/mcphost/pkg/llm/google"
 	"github.com/mark3labs/mcphost/pkg/llm/ollama"
-	"github.com/mark3labs/mcphost/pkg/llm/openai"
 )
 
 type MCPSession struct {
 	History      *history.HistoryMessage
-	AllTools     []llm.Tool
 	MCPClients   map[string]mcpclient.MCPClient
 	MCPServers   map[string]ServerConfigWrapper
 	Model        string
@@ -34,19 +32,84 @@ type MCPSession struct {
 	Verbose      bool
 	InTerminal   bool
 	DebugMode    bool
+
+	// ContextWindow overrides the model's context window when non-zero
+	ContextWindow int
+	// ReserveTokens is the part of the context window kept free for the response
+	ReserveTokens int
+	// budgetWarning warns once when there is no budget to prune history to
+	budgetWarning sync.Once
+
+	// CompactThreshold is the share of the context window at which older
+	// messages are summarized automatically, zero disables it
+	CompactThreshold float64
+	// CompactProvider writes the summaries, falling back to Provider when nil
+	CompactProvider llm.Provider
+	// TranscriptDir keeps a copy of the full history whenever it is compacted
+	TranscriptDir string
+
+	// OutputSchema is the JSON Schema final answers must match, if any
+	OutputSchema map[string]interface{}
+
+	// ToolChoice applies to the first model call of each prompt; follow-up
+	// calls after tool results always let the model decide
+	ToolChoice llm.ToolChoice
+
+	// MaxContinuations is how many times a reply cut off at the output token
+	// limit is continued
+	MaxContinuations int
+
+	// ToolApproval says which tool calls the user approves first: always,
+	// writes (tools not marked read-only) or never
+	ToolApproval string
+	// approvals are the tools the user always allows in this project
+	approvals *toolApprovals
+
+	// tools are the tools of every server, see AllTools
+	tools toolCatalog
+
+	// progress passes progress notifications on to the running tool call
+	progress progressTracker
+
+	// logLevel filters the log messages servers send, see LogLevel
+	logLevel   mcp.LoggingLevel
+	serverLogs map[string]*log.Logger
+	logFiles   []*os.File
+	logMu      sync.Mutex
+
+	// samplingProviders are reused across sampling requests, see
+	// samplingProvider
+	samplingProviders map[samplingProviderKey]llm.Provider
+	samplingMu        sync.Mutex
+
+	// roots are the directories offered to servers, see Roots
+	roots   []string
+	rootsMu sync.RWMutex
 }
 
 type InitConfig struct {
-	AnthropicAPIKey  string `json:"anthropicApiKey"`
-	AnthropicBaseURL string `json:"anthropicBaseUrl"`
-	OpenAIAPIKey     string `json:"openaiApiKey"`
-	OpenAIBaseURL    string `json:"openaiBaseUrl"`
-	GoogleAPIKey     string `json:"googleApiKey"`
-	ModelFlag        string `json:"modelFlag"`
-	SystemPromptFile string `json:"systemPromptFile"`
-	DebugMode        bool   `json:"debugMode"`
-	ConfigFile       string `json:"configFile"`
-	InTerminal       bool   `json:"inTerminal"`
+	AnthropicAPIKey  string   `json:"anthropicApiKey"`
+	AnthropicBaseURL string   `json:"anthropicBaseUrl"`
+	OpenAIAPIKey     string   `json:"openaiApiKey"`
+	OpenAIBaseURL    string   `json:"openaiBaseUrl"`
+	GoogleAPIKey     string   `json:"googleApiKey"`
+	ModelFlag        string   `json:"modelFlag"`
+	SystemPromptFile string   `json:"systemPromptFile"`
+	DebugMode        bool     `json:"debugMode"`
+	ConfigFile       string   `json:"configFile"`
+	InTerminal       bool     `json:"inTerminal"`
+	ContextWindow    int      `json:"contextWindow"`
+	ReserveTokens    int      `json:"reserveTokens"`
+	CompactThreshold float64  `json:"compactThreshold"`
+	CompactModel     string   `json:"compactModel"`
+	TranscriptDir    string   `json:"transcriptDir"`
+	OutputSchemaFile string   `json:"outputSchemaFile"`
+	ToolChoice       string   `json:"toolChoice"`
+	TraceHTTPDir     string   `json:"traceHttpDir"`
+	TraceTruncate    bool     `json:"traceTruncate"`
+	MaxContinuations int      `json:"maxContinuations"`
+	Roots            []string `json:"roots"`
+	ToolApproval     string   `json:"toolApproval"`
 }
 
 // Callback enums for message roles
@@ -56,6 +119,9 @@ const (
 	MODE_ASSISTANT_MESSAGE
 	MODE_RUN_TOOL
 	MODE_ERROR
+	MODE_COMPACT
+	MODE_STRUCTURED_OUTPUT
+	MODE_GET_PROMPT
 )
 
 func (ms *MCPSession) LoadMCPConfig(configPath string) error {
@@ -98,9 +164,11 @@ func (ms *MCPSession) LoadMCPConfig(configPath string) error {
 		)
 	}
 
-	if err := json.Unmarshal(configData, &ms.Config); err != nil {
-		return fmt.Errorf("error parsing config file: %w", err)
+	config, err := parseMCPConfig(configData)
+	if err != nil {
+		return fmt.Errorf("invalid config file %s:\n%w", configPath, err)
 	}
+	ms.Config = config
 
 	return nil
 }
@@ -116,6 +184,7 @@ func (ms *MCPSession) Close() error {
 			log.Info("Server closed", "name", name)
 		}
 	}
+	ms.closeServerLogs()
 	return err
 }
 
@@ -154,22 +223,59 @@ func (ms *MCPSession) RunPrompt(
 		role int,
 		action func(),
 	) error,
+) error {
+	return ms.runTurn(ctx, prompt, prompt != "", messages, callback)
+}
+
+// runTurn sends the history and prompt, if any, to the model and runs the
+// tools it calls. newTurn is set when the user started a turn, with a prompt
+// or a server prompt, rather than the model's answer to tool results; only
+// then is the history compacted and the tool choice applied.
+func (ms *MCPSession) runTurn(
+	ctx context.Context,
+	prompt string,
+	newTurn bool,
+	messages *[]history.HistoryMessage,
+	callback func(
+		ctx context.Context,
+		text string,
+		role int,
+		action func(),
+	) error,
 ) error {
 	// Display the user's prompt if it's not empty (i.e., not a tool response)
 	if prompt != "" {
 		callback(ctx, prompt, MODE_USER_PROMPT, nil)
+		resources, err := ms.attachResources(ctx, prompt)
+		if err != nil {
+			return err
+		}
 		*messages = append(
 			*messages,
 			history.HistoryMessage{
 				Role: "user",
-				Content: []history.ContentBlock{{
+				Content: append([]history.ContentBlock{{
 					Type: "text",
 					Text: prompt,
-				}},
+				}}, resources...),
 			},
 		)
 	}
 
+	// Summarize older messages at the start of a turn once the history gets
+	// close to the context limit, pruning below is the fallback
+	if newTurn && ms.needsCompaction(*messages) {
+		var compactErr error
+		callback(ctx, "", MODE_COMPACT, func() {
+			compactErr = ms.CompactMessages(ctx, messages)
+		})
+		if compactErr != nil {
+			log.Warn("Failed to compact conversation", "error", compactErr)
+		}
+	}
+
+	*messages = ms.pruneHistory(ctx, *messages)
+
 	var message llm.Message
 	var err error
 	backoff := initialBackoff
@@ -177,9 +283,20 @@ func (ms *MCPSession) RunPrompt(
 
 	// Convert MessageParam to llm.Message for provider
 	// Messages already implement llm.Message interface
-	llmMessages := make([]llm.Message, len(*messages))
-	for i := range *messages {
-		llmMessages[i] = &(*messages)[i]
+	llmMessages := ms.requestMessages(*messages)
+
+	var opts []llm.RequestOption
+	if ms.OutputSchema != nil {
+		opts = append(opts, llm.WithOutputSchema(ms.OutputSchema))
+	}
+	if newTurn {
+		choice, notice := ms.requestToolChoice()
+		if notice != "" {
+			callback(ctx, notice, MODE_ERROR, nil)
+		}
+		if !choice.IsAuto() {
+			opts = append(opts, llm.WithToolChoice(choice))
+		}
 	}
 
 	for {
@@ -188,7 +305,8 @@ func (ms *MCPSession) RunPrompt(
 				ctx,
 				prompt,
 				llmMessages,
-				ms.AllTools,
+				ms.requestTools(),
+				opts...,
 			)
 		}
 		callback(ctx, "", MODE_CREATE_MESSAGE, action)
@@ -206,7 +324,11 @@ func (ms *MCPSession) RunPrompt(
 					"attempt", retries+1,
 					"backoff", backoff.String())
 
-				time.Sleep(backoff)
+				select {
+				case <-time.After(backoff):
+				case <-ctx.Done():
+					return ms.cancelTurn(ctx, messages)
+				}
 				backoff *= 2
 				if backoff > maxBackoff {
 					backoff = maxBackoff
@@ -214,6 +336,9 @@ func (ms *MCPSession) RunPrompt(
 				retries++
 				continue
 			}
+			if ctx.Err() != nil {
+				return ms.cancelTurn(ctx, messages)
+			}
 			// If it's not an overloaded error, return the error immediately
 			return err
 		}
@@ -221,6 +346,23 @@ func (ms *MCPSession) RunPrompt(
 		break
 	}
 
+	message, err = ms.completeTruncated(ctx, prompt, llmMessages, opts, message, callback)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ms.cancelTurn(ctx, messages)
+		}
+		return err
+	}
+
+	if ms.OutputSchema != nil {
+		if done, err := ms.handleStructuredOutput(ctx, message, messages, callback); done {
+			if err != nil && ctx.Err() != nil {
+				return ms.cancelTurn(ctx, messages)
+			}
+			return err
+		}
+	}
+
 	var messageContent []history.ContentBlock
 
 	toolResults := []history.ContentBlock{}
@@ -280,24 +422,78 @@ func (ms *MCPSession) RunPrompt(
 			continue
 		}
 
+		// Every tool call still needs a result once the turn is cancelled,
+		// otherwise providers reject the history
+		if ctx.Err() != nil {
+			toolResults = append(toolResults, cancelledToolResult(toolCall.GetID()))
+			continue
+		}
+
+		// The model may still name a tool it is no longer offered
+		if !ms.toolEnabled(serverName, toolName) {
+			errMsg := fmt.Sprintf("Error calling tool %s: the tool is disabled", toolName)
+			callback(ctx, errMsg, MODE_ERROR, nil)
+			toolResults = append(toolResults, history.ContentBlock{
+				Type:      "tool_result",
+				ToolUseID: toolCall.GetID(),
+				Content: []history.ContentBlock{{
+					Type: "text",
+					Text: errMsg,
+				}},
+			})
+			continue
+		}
+
+		if denied := ms.approveToolCall(serverName, toolName, toolArgs); denied != "" {
+			callback(ctx, fmt.Sprintf("Tool call %s denied", toolName), MODE_ERROR, nil)
+			toolResults = append(toolResults, history.ContentBlock{
+				Type:      "tool_result",
+				ToolUseID: toolCall.GetID(),
+				Content: []history.ContentBlock{{
+					Type: "text",
+					Text: denied,
+				}},
+			})
+			continue
+		}
+
 		var toolResultPtr *mcp.CallToolResult
+		timeout := ms.serverOptions(serverName).Timeouts.callTimeout(toolName)
+		timedOut := false
 		action := func() {
+			// The transport tells the server to stop when the call times out
+			callCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			token, untrack := ms.progress.track(func(params mcp.ProgressNotificationParams) {
+				log.Debug("Tool progress", "tool", toolName,
+					"progress", params.Progress, "total", params.Total, "message", params.Message)
+				setSpinnerStatus(describeProgress(params))
+			})
+			defer untrack()
+
 			req := mcp.CallToolRequest{}
 			req.Params.Name = toolName
 			req.Params.Arguments = toolArgs
-			toolResultPtr, err = mcpClient.CallTool(
-				context.Background(),
-				req,
-			)
+			req.Params.Meta = &mcp.Meta{ProgressToken: token}
+			toolResultPtr, err = mcpClient.CallTool(callCtx, req)
+			timedOut = err != nil && callCtx.Err() == context.DeadlineExceeded
 		}
 		callback(ctx, toolName, MODE_RUN_TOOL, action)
 
+		if err != nil && ctx.Err() != nil {
+			toolResults = append(toolResults, cancelledToolResult(toolCall.GetID()))
+			continue
+		}
 		if err != nil {
 			errMsg := fmt.Sprintf(
 				"Error calling tool %s: %v",
 				toolName,
 				err,
 			)
+			if timedOut {
+				errMsg = fmt.Sprintf("Error calling tool %s: timed out after %s", toolName, timeout)
+			}
 
 			callback(ctx, errMsg, MODE_ERROR, nil)
 
@@ -355,14 +551,133 @@ func (ms *MCPSession) RunPrompt(
 				Content: []history.ContentBlock{toolResult},
 			})
 		}
+		if ctx.Err() != nil {
+			return ms.cancelTurn(ctx, messages)
+		}
 		// Make another call to get Claude's response to the tool results
-		return ms.RunPrompt(ctx, "", messages, callback)
+		return ms.runTurn(ctx, "", false, messages, callback)
 	}
 
 	// fmt.Println() // Add spacing
 	return nil
 }
 
+// cancelledNote ends a turn that was cancelled, so that the history does not
+// end in an unanswered prompt or tool result
+const cancelledNote = "(Cancelled by the user.)"
+
+// cancelTurn records that the current turn was cancelled and returns the
+// context's error
+func (ms *MCPSession) cancelTurn(ctx context.Context, messages *[]history.HistoryMessage) error {
+	if n := len(*messages); n > 0 && (*messages)[n-1].Role != "assistant" {
+		*messages = append(*messages, history.HistoryMessage{
+			Role:    "assistant",
+			Content: []history.ContentBlock{{Type: "text", Text: cancelledNote}},
+		})
+	}
+	return ctx.Err()
+}
+
+// cancelledToolResult is the result recorded for a tool call that was
+// cancelled or never started because the turn was cancelled
+func cancelledToolResult(toolUseID string) history.ContentBlock {
+	return history.ContentBlock{
+		Type:      "tool_result",
+		ToolUseID: toolUseID,
+		Content: []history.ContentBlock{{
+			Type: "text",
+			Text: "Tool call cancelled by the user",
+		}},
+	}
+}
+
+// contextWindow returns the size of the model's context window in tokens
+func (ms *MCPSession) contextWindow() int {
+	if ms.ContextWindow > 0 {
+		return ms.ContextWindow
+	}
+	return ms.Provider.Capabilities().ContextWindow
+}
+
+// availableTokens returns the part of the context window left for the
+// request. The reserve kept for the response is capped at what the model
+// can write and at a quarter of the window, so that a default reserve
+// larger than a small window does not leave nothing.
+func (ms *MCPSession) availableTokens() int {
+	window := ms.contextWindow()
+	reserve := ms.ReserveTokens
+	if limit := ms.Provider.Capabilities().MaxOutputTokens; limit > 0 {
+		reserve = min(reserve, limit)
+	}
+	return window - min(reserve, window/4)
+}
+
+// requestTools returns the tools to offer the model, none if it cannot call
+// tools
+func (ms *MCPSession) requestTools() []llm.Tool {
+	if !ms.Provider.Capabilities().Tools {
+		return nil
+	}
+	return ms.AllTools()
+}
+
+// requestMessages converts the history for a request, replacing images if
+// the model cannot see them
+func (ms *MCPSession) requestMessages(messages []history.HistoryMessage) []llm.Message {
+	if !ms.Provider.Capabilities().Vision {
+		messages = history.WithoutImages(messages)
+	}
+	llmMessages := make([]llm.Message, len(messages))
+	for i := range messages {
+		llmMessages[i] = &messages[i]
+	}
+	return llmMessages
+}
+
+// pruneHistory trims messages so that the next request fits in the context
+// window. Token counts are estimated heuristically; if the provider can count
+// tokens exactly and we are getting close to the limit, the exact count is
+// used to calibrate the estimates.
+func (ms *MCPSession) pruneHistory(
+	ctx context.Context,
+	messages []history.HistoryMessage,
+) []history.HistoryMessage {
+	available := ms.availableTokens()
+	if available <= 0 {
+		ms.budgetWarning.Do(func() {
+			log.Warn("Unknown context window, history is not pruned; set --context-window to enable it")
+		})
+		return messages
+	}
+
+	overhead := llm.EstimateTextTokens(ms.SystemPrompt) +
+		llm.EstimateToolTokens(ms.requestTools())
+	heuristic := history.EstimateTokens(messages) + overhead
+
+	ratio := 1.0
+	if counter, ok := ms.Provider.(llm.TokenCounter); ok && heuristic > available/2 {
+		exact, err := counter.CountTokens(ctx, ms.requestMessages(messages), ms.requestTools())
+		if err != nil {
+			log.Debug("Failed to count tokens, using estimate", "error", err)
+		} else if exact > 0 {
+			ratio = float64(exact) / float64(heuristic)
+			log.Debug("Calibrated token estimate",
+				"estimated", heuristic,
+				"exact", exact)
+		}
+	}
+
+	budget := available - int(float64(overhead)*ratio)
+	pruned := pruneMessages(messages, budget, func(msg history.HistoryMessage) int {
+		return int(float64(msg.EstimateTokens()) * ratio)
+	})
+	if dropped := len(messages) - len(pruned); dropped > 0 {
+		log.Warn("Dropped older messages to fit the context window, use /compact to summarize them instead",
+			"dropped", dropped)
+	}
+	return pruned
+}
+
 func NewSession(ctx context.Context, cfg InitConfig) (*MCPSession, error) {
 	// Set up logging based on debug flag
 	if cfg.DebugMode {
@@ -378,6 +693,15 @@ func NewSession(ctx context.Context, cfg InitConfig) (*MCPSession, error) {
 		MCPServers: make(map[string]ServerConfigWrapper),
 		Model:      cfg.ModelFlag,
 		Config:     &MCPConfig{},
+		InTerminal: cfg.InTerminal,
+
+		ContextWindow: cfg.ContextWindow,
+		ReserveTokens: cfg.ReserveTokens,
+
+		CompactThreshold: cfg.CompactThreshold,
+		TranscriptDir:    cfg.TranscriptDir,
+
+		MaxContinuations: cfg.MaxContinuations,
 	}
 
 	err := ms.LoadSystemPrompt(cfg.SystemPromptFile)
@@ -385,11 +709,34 @@ func NewSession(ctx context.Context, cfg InitConfig) (*MCPSession, error) {
 		return nil, fmt.Errorf("error loading system prompt: %v", err)
 	}
 
+	ms.OutputSchema, err = loadOutputSchema(cfg.OutputSchemaFile)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ms.LoadMCPConfig(cfg.ConfigFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading MCP config: %v", err)
+	}
+
+	if cfg.TraceHTTPDir != "" {
+		ms.Config.tracer, err = httpclient.NewTracer(cfg.TraceHTTPDir, cfg.TraceTruncate, apiKeySecrets()...)
+		if err != nil {
+			return nil, err
+		}
+		log.Info("Tracing provider HTTP traffic", "dir", ms.Config.tracer.Dir())
+	}
+
 	// Create the provider based on the model flag
 	err = ms.CreateProvider(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("error creating provider: %v", err)
 	}
+	if caps := ms.Provider.Capabilities(); ms.ContextWindow == 0 && caps.ContextWindowGuessed {
+		log.Warn("Unknown model, assuming a small context window; set --context-window to keep more history",
+			"model", cfg.ModelFlag,
+			"context_window", caps.ContextWindow)
+	}
 
 	// Validate model flag format
 	parts := strings.SplitN(cfg.ModelFlag, ":", 2)
@@ -403,117 +750,169 @@ func NewSession(ctx context.Context, cfg InitConfig) (*MCPSession, error) {
 		"provider", ms.Provider.Name(),
 		"model", parts[1])
 
-	err = ms.LoadMCPConfig(cfg.ConfigFile)
-	if err != nil {
-		return nil, fmt.Errorf("error loading MCP config: %v", err)
+	if cfg.CompactModel != "" {
+		ms.CompactProvider, err = createProvider(ctx, cfg.CompactModel, "", ms.Config)
+		if err != nil {
+			return nil, fmt.Errorf("error creating compaction provider: %v", err)
+		}
 	}
 
-	ms.MCPClients, err = createMCPClients(ms.Config)
-	if err != nil {
-		return nil, fmt.Errorf("error creating MCP clients: %v", err)
+	if provider, ok := ms.Provider.(*ollama.Provider); ok {
+		if err := ms.prepareOllamaModel(ctx, provider, parts[1]); err != nil {
+			return nil, err
+		}
 	}
 
-	for name := range ms.MCPClients {
-		log.Info("Server connected", "name", name)
+	if err := ms.initRoots(cfg.Roots); err != nil {
+		return nil, err
 	}
 
-	for serverName, mcpClient := range ms.MCPClients {
-		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-		toolsResult, err := mcpClient.ListTools(ctx, mcp.ListToolsRequest{})
-		cancel()
+	ms.MCPClients = createMCPClients(ms.Config, ms.clientOptions)
 
-		if err != nil {
-			log.Error(
-				"Error fetching tools",
+	var wg sync.WaitGroup
+	for serverName, mcpClient := range ms.MCPClients {
+		ms.watchNotifications(serverName, mcpClient)
+		if err := unavailable(mcpClient); err != nil {
+			ms.tools.set(serverName, serverTools{err: err})
+			continue
+		}
+		log.Info("Server connected", "name", serverName)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := ms.loadTools(ctx, serverName, mcpClient); err != nil {
+				log.Error(
+					"Error fetching tools",
+					"server",
+					serverName,
+					"error",
+					err,
+				)
+				return
+			}
+			log.Info(
+				"Tools loaded",
 				"server",
 				serverName,
-				"error",
-				err,
+				"count",
+				len(ms.tools.snapshot()[serverName].tools),
 			)
-			continue
+		}()
+	}
+	wg.Wait()
+
+	ms.superviseServers()
+
+	if len(ms.AllTools()) > 0 && !ms.Provider.Capabilities().Tools {
+		log.Warn("The model does not support tools, MCP tools will not be offered to it",
+			"model", ms.Model)
+	}
+
+	if cfg.ToolChoice != "" {
+		if err := ms.SetToolChoice(llm.ParseToolChoice(cfg.ToolChoice)); err != nil {
+			return nil, fmt.Errorf("invalid tool choice: %v", err)
 		}
+	}
 
-		serverTools := mcpToolsToAnthropicTools(serverName, toolsResult.Tools)
-		ms.AllTools = append(ms.AllTools, serverTools...)
-		log.Info(
-			"Tools loaded",
-			"server",
-			serverName,
-			"count",
-			len(toolsResult.Tools),
-		)
+	if err := ms.initToolApproval(cfg.ToolApproval); err != nil {
+		return nil, err
 	}
 
 	// messages := make([]history.HistoryMessage, 0)
 	return ms, nil
 }
 
-// Add new function to create provider
-func (ms *MCPSession) CreateProvider(ctx context.Context) error {
-	if ms.SystemPrompt == "" {
-		return fmt.Errorf("system prompt is not set")
+// clientOptions returns the options for the client of a server, with the
+// handlers for the requests servers can send us
+func (ms *MCPSession) clientOptions(server string) []mcpclient.ClientOption {
+	return []mcpclient.ClientOption{
+		mcpclient.WithSamplingHandler(&samplingHandler{ms: ms, server: server}),
+		mcpclient.WithRootsHandler(&rootsHandler{ms: ms}),
+		mcpclient.WithElicitationHandler(&elicitationHandler{ms: ms, server: server}),
 	}
-	if ms.Model == "" {
-		return fmt.Errorf("model is not set")
+}
+
+// serverOptions returns the settings shared by all transports of a server
+func (ms *MCPSession) serverOptions(server string) ServerOptions {
+	if ms.Config == nil {
+		return ServerOptions{}
 	}
-	parts := strings.SplitN(ms.Model, ":", 2)
-	if len(parts) < 2 {
-		return fmt.Errorf(
-			"invalid model format. Expected provider:model, got %s",
-			ms.Model,
-		)
+	wrapper, ok := ms.Config.MCPServers[server]
+	if !ok || wrapper.Config == nil {
+		return ServerOptions{}
 	}
+	return wrapper.Config.GetOptions()
+}
 
-	provider := parts[0]
-	model := parts[1]
-	var err error
-
-	switch provider {
-	case "anthropic":
-		apiKey := anthropicAPIKey
-		if apiKey == "" {
-			apiKey = os.Getenv("ANTHROPIC_API_KEY")
+// SetToolChoice validates and sets the tool choice used for new prompts
+func (ms *MCPSession) SetToolChoice(choice llm.ToolChoice) error {
+	if choice.Mode != llm.ToolChoiceAuto && choice.Mode != llm.ToolChoiceNone &&
+		!ms.Provider.Capabilities().Tools {
+		return fmt.Errorf("the model does not support tools")
+	}
+	if choice.Mode == llm.ToolChoiceTool {
+		found := false
+		for _, tool := range ms.AllTools() {
+			if tool.Name == choice.Name {
+				found = true
+				break
+			}
 		}
-
-		if apiKey == "" {
-			return fmt.Errorf(
-				"Anthropic API key not provided. Use --anthropic-api-key flag or ANTHROPIC_API_KEY environment variable",
-			)
+		if !found {
+			return fmt.Errorf("unknown tool %q, use the server__tool name shown by /tools", choice.Name)
 		}
-		ms.Provider = anthropic.NewProvider(apiKey, anthropicBaseURL, model, ms.SystemPrompt)
-		return err
-
-	case "ollama":
-		ms.Provider, err = ollama.NewProvider(model, ms.SystemPrompt)
-		return err
+	}
 
-	case "openai":
-		apiKey := openaiAPIKey
-		if apiKey == "" {
-			apiKey = os.Getenv("OPENAI_API_KEY")
+	if validator, ok := ms.Provider.(llm.ToolChoiceValidator); ok {
+		if err := validator.ValidateToolChoice(choice); err != nil {
+			return err
 		}
+	}
 
-		if apiKey == "" {
-			return fmt.Errorf(
-				"OpenAI API key not provided. Use --openai-api-key flag or OPENAI_API_KEY environment variable",
-			)
-		}
-		ms.Provider = openai.NewProvider(apiKey, openaiBaseURL, model, ms.SystemPrompt)
-		return nil
+	ms.ToolChoice = choice
+	return nil
+}
 
-	case "google":
-		apiKey := googleAPIKey
-		if apiKey == "" {
-			apiKey = os.Getenv("GOOGLE_API_KEY")
+// requestToolChoice returns the tool choice for the next request. When the
+// tools changed since the choice was set, by /tools disable or a server
+// reloading its tools, a forced choice may not be possible; the request then
+// lets the model decide, since providers reject choices of tools they are
+// not sent, and notice says so. ToolChoice is kept for when the tool returns.
+func (ms *MCPSession) requestToolChoice() (choice llm.ToolChoice, notice string) {
+	tools := ms.requestTools()
+	switch ms.ToolChoice.Mode {
+	case llm.ToolChoiceRequired:
+		if len(tools) > 0 {
+			return ms.ToolChoice, ""
 		}
-		if apiKey == "" {
-			// The project structure is provider specific, but Google calls this GEMINI_API_KEY in e.g. AI Studio. Support both.
-			apiKey = os.Getenv("GEMINI_API_KEY")
+		notice = "Tool choice required ignored: no tools are available"
+	case llm.ToolChoiceTool:
+		for _, tool := range tools {
+			if tool.Name == ms.ToolChoice.Name {
+				return ms.ToolChoice, ""
+			}
 		}
-		ms.Provider, err = google.NewProvider(ctx, apiKey, model, ms.SystemPrompt)
-		return err
-
+		notice = fmt.Sprintf("Tool choice %s ignored: the tool is not available", ms.ToolChoice.Name)
 	default:
-		return fmt.Errorf("unsupported provider: %s", provider)
+		return ms.ToolChoice, ""
 	}
+	return llm.ToolChoice{Mode: llm.ToolChoiceAuto}, notice
+}
+
+// CreateProvider creates the provider for the session's model
+func (ms *MCPSession) CreateProvider(ctx context.Context) error {
+	if ms.SystemPrompt == "" {
+		return fmt.Errorf("system prompt is not set")
+	}
+	if ms.Model == "" {
+		return fmt.Errorf("model is not set")
+	}
+
+	provider, err := createProvider(ctx, ms.Model, ms.SystemPrompt, ms.Config)
+	if err != nil {
+		return err
+	}
+	ms.Provider = provider
+	return nil
 }
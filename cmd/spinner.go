@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	spinnerStyle     = lipgloss.NewStyle().Foreground(tokyoBlue)
+	spinnerHintStyle = lipgloss.NewStyle().Foreground(tokyoFg).Faint(true)
+)
+
+var (
+	// activeSpinner is the spinner program currently running, if any
+	activeSpinner   *tea.Program
+	activeSpinnerMu sync.Mutex
+
+	// askMu lets one question at a time take over the terminal
+	askMu sync.Mutex
+)
+
+// actionDoneMsg tells the spinner that its action has returned
+type actionDoneMsg struct{}
+
+// spinnerStatusMsg replaces the status shown after the spinner's title
+type spinnerStatusMsg string
+
+// cancelSpinner shows a spinner while an action runs. Esc or Ctrl+C cancel
+// the action through its context instead of quitting the program; the
+// spinner stays up until the action has actually returned.
+type cancelSpinner struct {
+	spinner   spinner.Model
+	title     string
+	status    string
+	action    func()
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+func (m *cancelSpinner) Init() tea.Cmd {
+	return tea.Batch(m.spinner.Tick, func() tea.Msg {
+		m.action()
+		return actionDoneMsg{}
+	})
+}
+
+func (m *cancelSpinner) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc", "ctrl+c":
+			if !m.cancelled {
+				m.cancelled = true
+				m.cancel()
+			}
+		}
+		return m, nil
+	case actionDoneMsg:
+		return m, tea.Quit
+	case spinnerStatusMsg:
+		m.status = string(msg)
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.spinner, cmd = m.spinner.Update(msg)
+	return m, cmd
+}
+
+func (m *cancelSpinner) View() string {
+	hint := "esc to cancel"
+	if m.cancelled {
+		hint = "cancelling..."
+	}
+	view := m.spinner.View() + " " + m.title
+	if m.status != "" {
+		view += " " + m.status
+	}
+	return view + " " + spinnerHintStyle.Render("("+hint+")")
+}
+
+// runCancellable runs action behind a spinner, calling cancel when the user
+// presses Esc or Ctrl+C
+func runCancellable(title string, cancel context.CancelFunc, action func()) error {
+	m := &cancelSpinner{
+		spinner: spinner.New(
+			spinner.WithSpinner(spinner.Dot),
+			spinner.WithStyle(spinnerStyle),
+		),
+		title:  title,
+		action: action,
+		cancel: cancel,
+	}
+	p := tea.NewProgram(m)
+	activeSpinnerMu.Lock()
+	activeSpinner = p
+	activeSpinnerMu.Unlock()
+	defer func() {
+		activeSpinnerMu.Lock()
+		activeSpinner = nil
+		activeSpinnerMu.Unlock()
+	}()
+
+	_, err := p.Run()
+	return err
+}
+
+// askUser runs ask, which shows a form, while a server request is handled.
+// Such requests usually arrive while a tool call spinner is running, so the
+// spinner gives up the terminal until ask returns.
+func askUser(ask func() error) error {
+	askMu.Lock()
+	defer askMu.Unlock()
+
+	activeSpinnerMu.Lock()
+	p := activeSpinner
+	activeSpinnerMu.Unlock()
+
+	if p != nil {
+		if err := p.ReleaseTerminal(); err != nil {
+			return err
+		}
+		defer p.RestoreTerminal()
+	}
+	return ask()
+}
+
+// setSpinnerStatus shows status after the title of the running spinner
+func setSpinnerStatus(status string) {
+	activeSpinnerMu.Lock()
+	p := activeSpinner
+	activeSpinnerMu.Unlock()
+	if p != nil {
+		p.Send(spinnerStatusMsg(status))
+	}
+}
+
+// terminalWriter writes to stderr, or above the spinner while one is
+// running so that the output does not tear it
+type terminalWriter struct{}
+
+func (terminalWriter) Write(b []byte) (int, error) {
+	activeSpinnerMu.Lock()
+	p := activeSpinner
+	activeSpinnerMu.Unlock()
+	if p != nil {
+		p.Println(strings.TrimRight(string(b), "\n"))
+		return len(b), nil
+	}
+	return os.Stderr.Write(b)
+}
@@ -0,0 +1,516 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/huh/spinner"
+	"github.com/charmbracelet/log"
+	mcpclient "github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// pingInterval is how often servers are checked
+	pingInterval = 30 * time.Second
+	// pingTimeout bounds one ping
+	pingTimeout = 10 * time.Second
+	// pingRetryDelay is the wait before a server that missed a ping is
+	// checked again, before it is restarted
+	pingRetryDelay = 2 * time.Second
+	// maxRestartAttempts is how often reconnecting is tried before the
+	// server is given up on
+	maxRestartAttempts = 5
+	// restartBackoff is the wait after the first failed restart; it
+	// doubles with each further attempt
+	restartBackoff = time.Second
+)
+
+// serverState is the health of a server as shown by /servers
+type serverState string
+
+const (
+	serverStarting serverState = "starting"
+	serverReady    serverState = "ready"
+	serverDegraded serverState = "degraded"
+	serverFailed   serverState = "failed"
+)
+
+// supervisedClient is the client of one server. Once supervised it pings
+// the server periodically and reconnects, restarting stdio servers, when the
+// server stops answering. Requests made while it reconnects fail with an
+// error saying so, instead of with a broken transport.
+type supervisedClient struct {
+	name    string
+	connect func() (*mcpclient.Client, error)
+
+	mu     sync.RWMutex
+	client *mcpclient.Client
+	state  serverState
+	err    error
+	// handlers are registered again on every new client
+	handlers []func(notification mcp.JSONRPCNotification)
+
+	// onReconnect runs after the server has been restarted
+	onReconnect func()
+	// restartMu lets one restart at a time replace the client
+	restartMu sync.Mutex
+
+	check     chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newSupervisedClient connects to the server with connect, which is called
+// again whenever the server has to be restarted. If connecting fails the
+// client starts out failed.
+func newSupervisedClient(name string, connect func() (*mcpclient.Client, error)) *supervisedClient {
+	c := &supervisedClient{
+		name:    name,
+		connect: connect,
+		state:   serverReady,
+		check:   make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	client, err := connect()
+	if err != nil {
+		c.state, c.err = serverFailed, err
+	}
+	c.client = client
+	return c
+}
+
+// unavailable returns why a client cannot take requests, nil if it can
+func unavailable(client mcpclient.MCPClient) error {
+	if c, ok := client.(*supervisedClient); ok {
+		_, err := c.current()
+		return err
+	}
+	return nil
+}
+
+// State returns the server's state and the error that caused it, if any
+func (c *supervisedClient) State() (serverState, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.state, c.err
+}
+
+func (c *supervisedClient) setState(state serverState, err error) {
+	c.mu.Lock()
+	c.state, c.err = state, err
+	c.mu.Unlock()
+}
+
+// current returns the connected client, or an error if the server cannot
+// take requests right now
+func (c *supervisedClient) current() (*mcpclient.Client, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	switch {
+	case c.state == serverFailed:
+		return nil, fmt.Errorf("server %s is not running: %v", c.name, c.err)
+	case c.client == nil:
+		return nil, fmt.Errorf("server %s is restarting, try again shortly", c.name)
+	}
+	return c.client, nil
+}
+
+// requestCheck makes the supervisor ping the server now
+func (c *supervisedClient) requestCheck() {
+	select {
+	case c.check <- struct{}{}:
+	default:
+	}
+}
+
+// supervise checks the server until the client is closed
+func (c *supervisedClient) supervise() {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		case <-c.check:
+		}
+
+		if state, _ := c.State(); state == serverFailed || state == serverStarting {
+			continue
+		}
+		err := c.ping()
+		if err == nil {
+			c.setState(serverReady, nil)
+			continue
+		}
+		c.setState(serverDegraded, err)
+		log.Warn("Server is not responding", "server", c.name, "error", err)
+
+		// A single missed ping may just be a busy server
+		select {
+		case <-c.done:
+			return
+		case <-time.After(pingRetryDelay):
+		}
+		if err := c.ping(); err == nil {
+			c.setState(serverReady, nil)
+			continue
+		}
+		c.restart()
+	}
+}
+
+func (c *supervisedClient) ping() error {
+	client, err := c.current()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	return client.Ping(ctx)
+}
+
+// restart closes the client and connects again, waiting longer after each
+// failed attempt, until the server is back or maxRestartAttempts is reached
+func (c *supervisedClient) restart() {
+	c.restartMu.Lock()
+	defer c.restartMu.Unlock()
+	c.disconnect()
+
+	delay := restartBackoff
+	var err error
+	for attempt := 1; attempt <= maxRestartAttempts; attempt++ {
+		log.Info("Restarting server", "server", c.name, "attempt", attempt)
+		if err = c.reconnect(); err == nil {
+			log.Info("Server restarted", "server", c.name)
+			return
+		}
+		log.Warn("Failed to restart server", "server", c.name, "attempt", attempt, "error", err)
+
+		select {
+		case <-c.done:
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+
+	c.setState(serverFailed, err)
+	log.Error("Giving up on server", "server", c.name, "error", err)
+}
+
+// Reconnect closes the client and connects once more, whatever the state
+// of the server
+func (c *supervisedClient) Reconnect() error {
+	c.restartMu.Lock()
+	defer c.restartMu.Unlock()
+	c.disconnect()
+
+	if err := c.reconnect(); err != nil {
+		c.setState(serverFailed, err)
+		return err
+	}
+	return nil
+}
+
+// disconnect closes the client, leaving the server starting
+func (c *supervisedClient) disconnect() {
+	c.mu.Lock()
+	old := c.client
+	c.client, c.state = nil, serverStarting
+	c.mu.Unlock()
+	if old != nil {
+		old.Close()
+	}
+}
+
+// reconnect connects a new client and runs onReconnect once it is in place
+func (c *supervisedClient) reconnect() error {
+	client, err := c.connect()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	select {
+	case <-c.done:
+		// Closed while connecting
+		c.mu.Unlock()
+		client.Close()
+		return fmt.Errorf("server %s was closed", c.name)
+	default:
+	}
+	for _, handler := range c.handlers {
+		client.OnNotification(handler)
+	}
+	c.client, c.state, c.err = client, serverReady, nil
+	c.mu.Unlock()
+
+	if c.onReconnect != nil {
+		c.onReconnect()
+	}
+	return nil
+}
+
+// call runs a request on the connected client. Failed requests make the
+// supervisor check the server, unless the caller gave up on them.
+func call[T any](c *supervisedClient, ctx context.Context, request func(*mcpclient.Client) (T, error)) (T, error) {
+	client, err := c.current()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	result, err := request(client)
+	if err != nil && !errors.Is(ctx.Err(), context.Canceled) {
+		c.requestCheck()
+	}
+	return result, err
+}
+
+func (c *supervisedClient) Initialize(
+	ctx context.Context,
+	request mcp.InitializeRequest,
+) (*mcp.InitializeResult, error) {
+	return call(c, ctx, func(client *mcpclient.Client) (*mcp.InitializeResult, error) {
+		return client.Initialize(ctx, request)
+	})
+}
+
+func (c *supervisedClient) Ping(ctx context.Context) error {
+	_, err := call(c, ctx, func(client *mcpclient.Client) (struct{}, error) {
+		return struct{}{}, client.Ping(ctx)
+	})
+	return err
+}
+
+func (c *supervisedClient) ListResourcesByPage(
+	ctx context.Context,
+	request mcp.ListResourcesRequest,
+) (*mcp.ListResourcesResult, error) {
+	return call(c, ctx, func(client *mcpclient.Client) (*mcp.ListResourcesResult, error) {
+		return client.ListResourcesByPage(ctx, request)
+	})
+}
+
+func (c *supervisedClient) ListResources(
+	ctx context.Context,
+	request mcp.ListResourcesRequest,
+) (*mcp.ListResourcesResult, error) {
+	return call(c, ctx, func(client *mcpclient.Client) (*mcp.ListResourcesResult, error) {
+		return client.ListResources(ctx, request)
+	})
+}
+
+func (c *supervisedClient) ListResourceTemplatesByPage(
+	ctx context.Context,
+	request mcp.ListResourceTemplatesRequest,
+) (*mcp.ListResourceTemplatesResult, error) {
+	return call(c, ctx, func(client *mcpclient.Client) (*mcp.ListResourceTemplatesResult, error) {
+		return client.ListResourceTemplatesByPage(ctx, request)
+	})
+}
+
+func (c *supervisedClient) ListResourceTemplates(
+	ctx context.Context,
+	request mcp.ListResourceTemplatesRequest,
+) (*mcp.ListResourceTemplatesResult, error) {
+	return call(c, ctx, func(client *mcpclient.Client) (*mcp.ListResourceTemplatesResult, error) {
+		return client.ListResourceTemplates(ctx, request)
+	})
+}
+
+func (c *supervisedClient) ReadResource(
+	ctx context.Context,
+	request mcp.ReadResourceRequest,
+) (*mcp.ReadResourceResult, error) {
+	return call(c, ctx, func(client *mcpclient.Client) (*mcp.ReadResourceResult, error) {
+		return client.ReadResource(ctx, request)
+	})
+}
+
+func (c *supervisedClient) Subscribe(ctx context.Context, request mcp.SubscribeRequest) error {
+	_, err := call(c, ctx, func(client *mcpclient.Client) (struct{}, error) {
+		return struct{}{}, client.Subscribe(ctx, request)
+	})
+	return err
+}
+
+func (c *supervisedClient) Unsubscribe(ctx context.Context, request mcp.UnsubscribeRequest) error {
+	_, err := call(c, ctx, func(client *mcpclient.Client) (struct{}, error) {
+		return struct{}{}, client.Unsubscribe(ctx, request)
+	})
+	return err
+}
+
+func (c *supervisedClient) ListPromptsByPage(
+	ctx context.Context,
+	request mcp.ListPromptsRequest,
+) (*mcp.ListPromptsResult, error) {
+	return call(c, ctx, func(client *mcpclient.Client) (*mcp.ListPromptsResult, error) {
+		return client.ListPromptsByPage(ctx, request)
+	})
+}
+
+func (c *supervisedClient) ListPrompts(
+	ctx context.Context,
+	request mcp.ListPromptsRequest,
+) (*mcp.ListPromptsResult, error) {
+	return call(c, ctx, func(client *mcpclient.Client) (*mcp.ListPromptsResult, error) {
+		return client.ListPrompts(ctx, request)
+	})
+}
+
+func (c *supervisedClient) GetPrompt(
+	ctx context.Context,
+	request mcp.GetPromptRequest,
+) (*mcp.GetPromptResult, error) {
+	return call(c, ctx, func(client *mcpclient.Client) (*mcp.GetPromptResult, error) {
+		return client.GetPrompt(ctx, request)
+	})
+}
+
+func (c *supervisedClient) ListToolsByPage(
+	ctx context.Context,
+	request mcp.ListToolsRequest,
+) (*mcp.ListToolsResult, error) {
+	return call(c, ctx, func(client *mcpclient.Client) (*mcp.ListToolsResult, error) {
+		return client.ListToolsByPage(ctx, request)
+	})
+}
+
+func (c *supervisedClient) ListTools(
+	ctx context.Context,
+	request mcp.ListToolsRequest,
+) (*mcp.ListToolsResult, error) {
+	return call(c, ctx, func(client *mcpclient.Client) (*mcp.ListToolsResult, error) {
+		return client.ListTools(ctx, request)
+	})
+}
+
+func (c *supervisedClient) CallTool(
+	ctx context.Context,
+	request mcp.CallToolRequest,
+) (*mcp.CallToolResult, error) {
+	return call(c, ctx, func(client *mcpclient.Client) (*mcp.CallToolResult, error) {
+		return client.CallTool(ctx, request)
+	})
+}
+
+func (c *supervisedClient) SetLevel(ctx context.Context, request mcp.SetLevelRequest) error {
+	_, err := call(c, ctx, func(client *mcpclient.Client) (struct{}, error) {
+		return struct{}{}, client.SetLevel(ctx, request)
+	})
+	return err
+}
+
+func (c *supervisedClient) Complete(
+	ctx context.Context,
+	request mcp.CompleteRequest,
+) (*mcp.CompleteResult, error) {
+	return call(c, ctx, func(client *mcpclient.Client) (*mcp.CompleteResult, error) {
+		return client.Complete(ctx, request)
+	})
+}
+
+// RootListChanges tells the server the roots changed, see
+// rootsChangedNotifier
+func (c *supervisedClient) RootListChanges(ctx context.Context) error {
+	_, err := call(c, ctx, func(client *mcpclient.Client) (struct{}, error) {
+		return struct{}{}, client.RootListChanges(ctx)
+	})
+	return err
+}
+
+// GetServerCapabilities returns what the server announced, nothing while
+// it is not connected
+func (c *supervisedClient) GetServerCapabilities() mcp.ServerCapabilities {
+	client, err := c.current()
+	if err != nil {
+		return mcp.ServerCapabilities{}
+	}
+	return client.GetServerCapabilities()
+}
+
+func (c *supervisedClient) OnNotification(handler func(notification mcp.JSONRPCNotification)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers = append(c.handlers, handler)
+	if c.client != nil {
+		c.client.OnNotification(handler)
+	}
+}
+
+// Close stops supervising the server and closes its client
+func (c *supervisedClient) Close() error {
+	c.closeOnce.Do(func() { close(c.done) })
+
+	c.mu.Lock()
+	client := c.client
+	c.client = nil
+	c.mu.Unlock()
+	if client == nil {
+		return nil
+	}
+	return client.Close()
+}
+
+// superviseServers starts checking every server. Restarted servers get
+// their tools listed again and the log level set with /loglevel.
+func (ms *MCPSession) superviseServers() {
+	for name, client := range ms.MCPClients {
+		supervised, ok := client.(*supervisedClient)
+		if !ok {
+			continue
+		}
+		supervised.onReconnect = func() {
+			ms.reloadTools(name, supervised)
+
+			ms.logMu.Lock()
+			level := ms.logLevel
+			ms.logMu.Unlock()
+			if level == "" || !hasLogging(supervised) {
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), setLevelTimeout)
+			defer cancel()
+			req := mcp.SetLevelRequest{}
+			req.Params.Level = level
+			if err := supervised.SetLevel(ctx, req); err != nil {
+				log.Warn("Failed to set log level of restarted server", "server", name, "error", err)
+			}
+		}
+		go supervised.supervise()
+	}
+}
+
+func handleReconnectCommand(ms *MCPSession, args []string) {
+	if len(args) != 1 {
+		fmt.Printf("\n%s\n\n", errorStyle.Render("Usage: /reconnect <name>"))
+		return
+	}
+	name := args[0]
+	client, ok := ms.MCPClients[name].(*supervisedClient)
+	if !ok {
+		fmt.Printf("\n%s\n\n", errorStyle.Render(fmt.Sprintf("Unknown server %s, see /servers", name)))
+		return
+	}
+
+	var err error
+	_ = spinner.New().
+		Title(fmt.Sprintf("Reconnecting to %s...", name)).
+		Action(func() { err = client.Reconnect() }).
+		Run()
+	if err != nil {
+		fmt.Printf("\n%s\n\n", errorStyle.Render(fmt.Sprintf("Error reconnecting to %s: %v", name, err)))
+		return
+	}
+	fmt.Printf("\n%s\n\n", promptStyle.Render(fmt.Sprintf(
+		"Reconnected to %s, %d tools available",
+		name,
+		len(ms.tools.snapshot()[name].tools),
+	)))
+}
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+
+	"github.com/charmbracelet/log"
+	mcpclient "github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcphost/pkg/llm"
+)
+
+// toolCatalog holds the tools of every server as last listed. It is
+// updated when a server reports that its tools changed, while prompts and
+// /tools read it, so all access goes through the mutex.
+type toolCatalog struct {
+	mu      sync.RWMutex
+	servers map[string]serverTools
+	// overrides are the /tools enable and disable commands, oldest first
+	overrides []toolOverride
+}
+
+// toolOverride enables or disables the tools whose server__tool names
+// match a glob pattern
+type toolOverride struct {
+	pattern string
+	enabled bool
+}
+
+// serverTools is the result of listing the tools of one server
+type serverTools struct {
+	tools []mcp.Tool
+	err   error
+}
+
+func (c *toolCatalog) set(server string, result serverTools) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.servers == nil {
+		c.servers = make(map[string]serverTools)
+	}
+	c.servers[server] = result
+}
+
+// snapshot returns the tools of every server
+func (c *toolCatalog) snapshot() map[string]serverTools {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	servers := make(map[string]serverTools, len(c.servers))
+	for name, result := range c.servers {
+		servers[name] = result
+	}
+	return servers
+}
+
+// llmTools returns the tools of every server that enabled accepts under
+// their server__tool names, ordered by server
+func (c *toolCatalog) llmTools(enabled func(server, tool string) bool) []llm.Tool {
+	servers := c.snapshot()
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var tools []llm.Tool
+	for _, name := range names {
+		var serverTools []mcp.Tool
+		for _, tool := range servers[name].tools {
+			if enabled(name, tool.Name) {
+				serverTools = append(serverTools, tool)
+			}
+		}
+		tools = append(tools, mcpToolsToAnthropicTools(name, serverTools)...)
+	}
+	return tools
+}
+
+// find returns a tool of a server as last listed
+func (c *toolCatalog) find(server, name string) (mcp.Tool, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, tool := range c.servers[server].tools {
+		if tool.Name == name {
+			return tool, true
+		}
+	}
+	return mcp.Tool{}, false
+}
+
+func (c *toolCatalog) override(pattern string, enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.overrides = append(c.overrides, toolOverride{pattern: pattern, enabled: enabled})
+}
+
+// overridden returns whether the latest override matching name enables
+// the tool, and false for ok if none matches
+func (c *toolCatalog) overridden(name string) (enabled bool, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for i := len(c.overrides) - 1; i >= 0; i-- {
+		if matched, _ := path.Match(c.overrides[i].pattern, name); matched {
+			return c.overrides[i].enabled, true
+		}
+	}
+	return false, false
+}
+
+// AllTools returns the enabled tools of every server as the model sees them
+func (ms *MCPSession) AllTools() []llm.Tool {
+	return ms.tools.llmTools(ms.toolEnabled)
+}
+
+// toolEnabled reports whether the model may use a tool. /tools enable and
+// disable win over the server's allowedTools and disabledTools.
+func (ms *MCPSession) toolEnabled(serverName, toolName string) bool {
+	if enabled, ok := ms.tools.overridden(serverName + "__" + toolName); ok {
+		return enabled
+	}
+	opts := ms.serverOptions(serverName)
+	if len(opts.AllowedTools) > 0 && !matchAny(opts.AllowedTools, toolName) {
+		return false
+	}
+	return !matchAny(opts.DisabledTools, toolName)
+}
+
+// checkToolPattern reports whether pattern is a valid glob pattern
+func checkToolPattern(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid glob pattern %q", pattern)
+	}
+	return nil
+}
+
+// matchAny reports whether name matches one of the glob patterns
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// loadTools lists the tools of a server and stores them in the catalog.
+// Errors are stored as well, so /tools can show them.
+func (ms *MCPSession) loadTools(ctx context.Context, serverName string, client mcpclient.MCPClient) error {
+	ctx, cancel := context.WithTimeout(ctx, ms.serverOptions(serverName).Timeouts.listTimeout())
+	defer cancel()
+
+	// The client follows nextCursor until every page is read
+	result, err := client.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		ms.tools.set(serverName, serverTools{err: err})
+		return err
+	}
+	ms.tools.set(serverName, serverTools{tools: result.Tools})
+	return nil
+}
+
+// reloadTools lists a server's tools again after they changed or the
+// server was restarted
+func (ms *MCPSession) reloadTools(serverName string, client mcpclient.MCPClient) {
+	if err := ms.loadTools(context.Background(), serverName, client); err != nil {
+		log.Error("Error reloading tools", "server", serverName, "error", err)
+		return
+	}
+	log.Info("Tools reloaded", "server", serverName, "count", len(ms.tools.snapshot()[serverName].tools))
+}
+
+// setToolsEnabled enables or disables the tools whose server__tool names
+// match pattern and returns how many of the listed tools match
+func (ms *MCPSession) setToolsEnabled(pattern string, enabled bool) (int, error) {
+	if err := checkToolPattern(pattern); err != nil {
+		return 0, err
+	}
+	ms.tools.override(pattern, enabled)
+
+	matched := 0
+	for serverName, result := range ms.tools.snapshot() {
+		for _, tool := range result.tools {
+			if ok, _ := path.Match(pattern, serverName+"__"+tool.Name); ok {
+				matched++
+			}
+		}
+	}
+	return matched, nil
+}
+
+func handleToolsToggleCommand(ms *MCPSession, args []string) {
+	if len(args) != 2 {
+		fmt.Printf("\n%s\n\n", errorStyle.Render("Usage: /tools [enable|disable <server__tool pattern>]"))
+		return
+	}
+	enabled := args[0] == "enable"
+	matched, err := ms.setToolsEnabled(args[1], enabled)
+	if err != nil {
+		fmt.Printf("\n%s\n\n", errorStyle.Render(err.Error()))
+		return
+	}
+
+	verb := "Disabled"
+	if enabled {
+		verb = "Enabled"
+	}
+	fmt.Printf("\n%s\n\n", promptStyle.Render(fmt.Sprintf("%s %d tools matching %s", verb, matched, args[1])))
+	if _, notice := ms.requestToolChoice(); notice != "" {
+		fmt.Printf("%s\n\n", errorStyle.Render(notice))
+	}
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/log"
+	mcpclient "github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// cancelNotificationTimeout bounds how long we try to tell a server that a
+// request was cancelled
+const cancelNotificationTimeout = 5 * time.Second
+
+// startMCPClient wraps the transport and starts a client on it. The
+// transport is started by the client, which also wires up notifications
+// and the handlers for requests from the server.
+func startMCPClient(t transport.Interface, options ...mcpclient.ClientOption) (*mcpclient.Client, error) {
+	client := mcpclient.NewClient(&cancellingTransport{Interface: t}, options...)
+	if err := client.Start(context.Background()); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// cancellingTransport sends notifications/cancelled when a request is
+// abandoned because its context was cancelled, so that the server can stop
+// working on it
+type cancellingTransport struct {
+	transport.Interface
+}
+
+func (t *cancellingTransport) SendRequest(
+	ctx context.Context,
+	request transport.JSONRPCRequest,
+) (*transport.JSONRPCResponse, error) {
+	response, err := t.Interface.SendRequest(ctx, request)
+	// The spec does not allow cancelling initialize
+	if err != nil && ctx.Err() != nil && request.Method != string(mcp.MethodInitialize) {
+		t.notifyCancelled(request.ID, ctx.Err().Error())
+	}
+	return response, err
+}
+
+func (t *cancellingTransport) notifyCancelled(id mcp.RequestId, reason string) {
+	ctx, cancel := context.WithTimeout(context.Background(), cancelNotificationTimeout)
+	defer cancel()
+
+	notification := mcp.JSONRPCNotification{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		Notification: mcp.Notification{
+			Method: "notifications/cancelled",
+			Params: mcp.NotificationParams{
+				AdditionalFields: map[string]any{
+					"requestId": id,
+					"reason":    reason,
+				},
+			},
+		},
+	}
+	if err := t.Interface.SendNotification(ctx, notification); err != nil {
+		log.Debug("Failed to send cancellation", "error", err)
+	}
+}
+
+// SetProtocolVersion passes the negotiated version on to HTTP transports,
+// which send it with every request
+func (t *cancellingTransport) SetProtocolVersion(version string) {
+	if conn, ok := t.Interface.(transport.HTTPConnection); ok {
+		conn.SetProtocolVersion(version)
+	}
+}
+
+// SetRequestHandler passes the handler for server to client requests on to
+// transports that support them
+func (t *cancellingTransport) SetRequestHandler(handler transport.RequestHandler) {
+	if bidirectional, ok := t.Interface.(transport.BidirectionalInterface); ok {
+		bidirectional.SetRequestHandler(handler)
+	}
+}
+
+// transportLogger sends the transport's own messages to our log, where they
+// only show up with --debug
+type transportLogger struct {
+	server string
+}
+
+func (l transportLogger) Infof(format string, v ...any) {
+	log.Debug(fmt.Sprintf(format, v...), "server", l.server)
+}
+
+func (l transportLogger) Errorf(format string, v ...any) {
+	log.Debug(fmt.Sprintf(format, v...), "server", l.server)
+}
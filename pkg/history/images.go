@@ -0,0 +1,50 @@
+package history
+
+import "github.com/mark3labs/mcp-go/mcp"
+
+// imagePlaceholder replaces images for models that cannot see them
+const imagePlaceholder = "[image omitted: the model does not accept images]"
+
+// WithoutImages returns a copy of messages in which images in tool results
+// are replaced by a short note. Messages without images are shared with the
+// original slice.
+func WithoutImages(messages []HistoryMessage) []HistoryMessage {
+	result := make([]HistoryMessage, len(messages))
+	for i, msg := range messages {
+		result[i] = msg
+		copied := false
+		for j, block := range msg.Content {
+			content, ok := block.Content.([]mcp.Content)
+			if !ok || !hasImage(content) {
+				continue
+			}
+			if !copied {
+				result[i].Content = append([]ContentBlock(nil), msg.Content...)
+				copied = true
+			}
+			result[i].Content[j].Content = replaceImages(content)
+		}
+	}
+	return result
+}
+
+func hasImage(content []mcp.Content) bool {
+	for _, c := range content {
+		if _, ok := c.(mcp.ImageContent); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func replaceImages(content []mcp.Content) []mcp.Content {
+	replaced := make([]mcp.Content, len(content))
+	for i, c := range content {
+		if _, ok := c.(mcp.ImageContent); ok {
+			replaced[i] = mcp.NewTextContent(imagePlaceholder)
+			continue
+		}
+		replaced[i] = c
+	}
+	return replaced
+}
@@ -18,10 +18,10 @@ func (m *HistoryMessage) GetRole() string {
 }
 
 func (m *HistoryMessage) GetContent() string {
-	// Concatenate all text content blocks
+	// Concatenate all text content blocks, and the text of attached resources
 	var content string
 	for _, block := range m.Content {
-		if block.Type == "text" {
+		if block.Type == "text" || block.Type == "resource" {
 			content += block.Text + " "
 		}
 	}
@@ -0,0 +1,66 @@
+package history
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ResourceBlock turns the contents of an MCP resource into a content block.
+// Content keeps the contents as read; Text is what models see, so binary
+// contents that are not text are only described.
+func ResourceBlock(contents mcp.ResourceContents) ContentBlock {
+	block := ContentBlock{Type: "resource", Content: contents}
+
+	switch c := contents.(type) {
+	case mcp.TextResourceContents:
+		block.Text = resourceText(c.URI, c.MIMEType, c.Text)
+	case mcp.BlobResourceContents:
+		data, err := base64.StdEncoding.DecodeString(c.Blob)
+		switch {
+		case err != nil:
+			block.Text = fmt.Sprintf("[resource %s could not be decoded: %v]", c.URI, err)
+		case isTextMIMEType(c.MIMEType):
+			block.Text = resourceText(c.URI, c.MIMEType, string(data))
+		default:
+			mimeType := c.MIMEType
+			if mimeType == "" {
+				mimeType = "unknown type"
+			}
+			block.Text = fmt.Sprintf("[binary resource %s (%s, %d bytes) is not shown]", c.URI, mimeType, len(data))
+		}
+	}
+	return block
+}
+
+func resourceText(uri, mimeType, text string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<resource uri=%q", uri)
+	if mimeType != "" {
+		fmt.Fprintf(&b, " mimeType=%q", mimeType)
+	}
+	b.WriteString(">\n")
+	b.WriteString(text)
+	b.WriteString("\n</resource>")
+	return b.String()
+}
+
+// isTextMIMEType reports whether blobs of this type can be shown as text
+func isTextMIMEType(mimeType string) bool {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/yaml",
+		"application/javascript", "application/x-yaml", "application/toml":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
@@ -0,0 +1,79 @@
+package history
+
+import (
+	"encoding/json"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcphost/pkg/llm"
+)
+
+// imageTokens is a flat estimate for an image. Providers bill images by
+// resolution rather than by the size of their base64 encoding.
+const imageTokens = 1600
+
+// messageOverheadTokens accounts for role markers and block framing
+const messageOverheadTokens = 4
+
+// EstimateTokens returns a heuristic token count for the message
+func (m *HistoryMessage) EstimateTokens() int {
+	total := messageOverheadTokens
+	for _, block := range m.Content {
+		total += estimateBlockTokens(block)
+	}
+	return total
+}
+
+// EstimateTokens returns a heuristic token count for a list of messages
+func EstimateTokens(messages []HistoryMessage) int {
+	total := 0
+	for i := range messages {
+		total += messages[i].EstimateTokens()
+	}
+	return total
+}
+
+func estimateBlockTokens(block ContentBlock) int {
+	total := llm.EstimateTextTokens(block.Text) +
+		llm.EstimateTextTokens(block.Name) +
+		llm.EstimateTextTokens(string(block.Input))
+
+	// Tool results keep their text in both Text and Content, so only count
+	// the structured content when it is present. Models only see the Text
+	// of resources.
+	if block.Content != nil && block.Type != "resource" {
+		total = llm.EstimateTextTokens(block.Name) +
+			llm.EstimateTextTokens(string(block.Input)) +
+			estimateContentTokens(block.Content)
+	}
+	return total
+}
+
+func estimateContentTokens(content interface{}) int {
+	switch v := content.(type) {
+	case string:
+		return llm.EstimateTextTokens(v)
+	case []ContentBlock:
+		total := 0
+		for _, block := range v {
+			total += estimateBlockTokens(block)
+		}
+		return total
+	case []mcp.Content:
+		total := 0
+		for _, c := range v {
+			switch item := c.(type) {
+			case mcp.TextContent:
+				total += llm.EstimateTextTokens(item.Text)
+			case mcp.ImageContent:
+				total += imageTokens
+			default:
+				raw, _ := json.Marshal(item)
+				total += llm.EstimateTextTokens(string(raw))
+			}
+		}
+		return total
+	default:
+		raw, _ := json.Marshal(v)
+		return llm.EstimateTextTokens(string(raw))
+	}
+}
@@ -0,0 +1,143 @@
+package httpclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Headers adds headers and query parameters to every request. Headers can
+// also come from a helper command run at request time, for short-lived
+// tokens; it must print one "Name: value" header per line.
+type Headers struct {
+	Static  map[string]string
+	Query   map[string]string
+	Command []string
+	// CommandTTL caches the command's output; zero runs it for every request
+	CommandTTL time.Duration
+
+	mu        sync.Mutex
+	cached    http.Header
+	fetchedAt time.Time
+	tracer    *Tracer
+}
+
+// HideFrom keeps the headers and query parameters out of the trace files
+// t writes, including the headers the command prints later
+func (h *Headers) HideFrom(t *Tracer) {
+	if h == nil || t == nil {
+		return
+	}
+	for name, value := range h.Static {
+		t.RedactHeaders(name)
+		t.AddSecrets(value)
+	}
+	for _, value := range h.Query {
+		t.AddSecrets(value)
+	}
+	h.mu.Lock()
+	h.tracer = t
+	h.mu.Unlock()
+}
+
+// IsZero reports whether h adds nothing to requests
+func (h *Headers) IsZero() bool {
+	return h == nil || len(h.Static) == 0 && len(h.Query) == 0 && len(h.Command) == 0
+}
+
+// Client returns a copy of client that adds the headers. A nil client is
+// treated as http.DefaultClient.
+func (h *Headers) Client(client *http.Client) *http.Client {
+	wrapped := &http.Client{}
+	if client != nil {
+		*wrapped = *client
+	}
+	base := wrapped.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	wrapped.Transport = &headerTransport{headers: h, base: base}
+	return wrapped
+}
+
+type headerTransport struct {
+	headers *Headers
+	base    http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	h := t.headers
+	req = req.Clone(req.Context())
+
+	for name, value := range h.Static {
+		req.Header.Set(name, value)
+	}
+
+	if len(h.Query) > 0 {
+		query := req.URL.Query()
+		for name, value := range h.Query {
+			query.Set(name, value)
+		}
+		req.URL.RawQuery = query.Encode()
+	}
+
+	if len(h.Command) > 0 {
+		dynamic, err := h.commandHeaders(req.Context())
+		if err != nil {
+			return nil, err
+		}
+		for name, values := range dynamic {
+			req.Header[name] = values
+		}
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+func (h *Headers) commandHeaders(ctx context.Context) (http.Header, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.cached != nil && h.CommandTTL > 0 && time.Since(h.fetchedAt) < h.CommandTTL {
+		return h.cached, nil
+	}
+
+	cmd := exec.CommandContext(ctx, h.Command[0], h.Command[1:]...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("headers command failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	headers := make(http.Header)
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("headers command printed %q, expected \"Name: value\"", line)
+		}
+		headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	if h.tracer != nil {
+		for name, values := range headers {
+			h.tracer.RedactHeaders(name)
+			h.tracer.AddSecrets(values...)
+		}
+	}
+
+	h.cached = headers
+	h.fetchedAt = time.Now()
+	return headers, nil
+}
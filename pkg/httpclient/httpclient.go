@@ -0,0 +1,156 @@
+// Package httpclient builds HTTP clients from user configuration, for
+// networks that need a proxy, a private CA or client certificates.
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Config describes how to reach an HTTP endpoint. The zero value behaves
+// like http.DefaultClient.
+type Config struct {
+	// Proxy is the URL of an HTTP or HTTPS proxy. When empty, the
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables are used.
+	Proxy string `json:"proxy,omitempty"`
+	// CACert is a PEM file with additional certificate authorities to trust
+	CACert string `json:"caCert,omitempty"`
+	// ClientCert and ClientKey are PEM files used for mutual TLS
+	ClientCert string `json:"clientCert,omitempty"`
+	ClientKey  string `json:"clientKey,omitempty"`
+	// ConnectTimeout limits how long establishing a connection may take
+	ConnectTimeout Duration `json:"connectTimeout,omitempty"`
+	// ResponseHeaderTimeout limits how long to wait for response headers
+	// after the request has been sent. It does not limit streaming bodies.
+	ResponseHeaderTimeout Duration `json:"responseHeaderTimeout,omitempty"`
+}
+
+// Duration is a time.Duration written as a string such as "30s" in JSON
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30s\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// IsZero reports whether the config changes nothing from the defaults
+func (c Config) IsZero() bool {
+	return c == Config{}
+}
+
+// Merge returns c with every field that is set in override replaced
+func (c Config) Merge(override Config) Config {
+	if override.Proxy != "" {
+		c.Proxy = override.Proxy
+	}
+	if override.CACert != "" {
+		c.CACert = override.CACert
+	}
+	if override.ClientCert != "" {
+		c.ClientCert = override.ClientCert
+	}
+	if override.ClientKey != "" {
+		c.ClientKey = override.ClientKey
+	}
+	if override.ConnectTimeout != 0 {
+		c.ConnectTimeout = override.ConnectTimeout
+	}
+	if override.ResponseHeaderTimeout != 0 {
+		c.ResponseHeaderTimeout = override.ResponseHeaderTimeout
+	}
+	return c
+}
+
+// New returns an HTTP client for the config. No overall request timeout is
+// set, as model responses and SSE streams can legitimately take minutes.
+func New(cfg Config) (*http.Client, error) {
+	transport, err := NewTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// NewTransport returns an HTTP transport for the config
+func NewTransport(cfg Config) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if cfg.ConnectTimeout > 0 {
+		dialer := &net.Dialer{
+			Timeout:   time.Duration(cfg.ConnectTimeout),
+			KeepAlive: 30 * time.Second,
+		}
+		transport.DialContext = dialer.DialContext
+		transport.TLSHandshakeTimeout = time.Duration(cfg.ConnectTimeout)
+	}
+	transport.ResponseHeaderTimeout = time.Duration(cfg.ResponseHeaderTimeout)
+
+	if cfg.CACert != "" || cfg.ClientCert != "" || cfg.ClientKey != "" {
+		tlsConfig, err := tlsConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return transport, nil
+}
+
+func tlsConfig(cfg Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.CACert != "" {
+		pem, err := os.ReadFile(cfg.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA certificate: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.ClientCert != "" || cfg.ClientKey != "" {
+		if cfg.ClientCert == "" || cfg.ClientKey == "" {
+			return nil, fmt.Errorf("clientCert and clientKey must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
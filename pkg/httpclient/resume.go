@@ -0,0 +1,177 @@
+package httpclient
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxResumeAttempts bounds how often a single event stream is resumed
+const maxResumeAttempts = 3
+
+// Resumable returns a copy of client whose Server-Sent Events responses
+// survive dropped connections. When a stream breaks after the server has
+// sent event IDs, it is resumed with a GET carrying Last-Event-ID, as the
+// MCP Streamable HTTP transport allows. Readers only ever see complete
+// events, so a replayed event never follows half of the original. A nil
+// client is treated as http.DefaultClient.
+func Resumable(client *http.Client) *http.Client {
+	wrapped := &http.Client{}
+	if client != nil {
+		*wrapped = *client
+	}
+	base := wrapped.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	wrapped.Transport = &resumeTransport{base: base}
+	return wrapped
+}
+
+type resumeTransport struct {
+	base http.RoundTripper
+}
+
+func (t *resumeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/event-stream" {
+		return resp, nil
+	}
+	resp.Body = &resumableBody{transport: t, req: req, body: resp.Body}
+	return resp, nil
+}
+
+// resumableBody passes complete events through and reconnects when the
+// underlying stream fails
+type resumableBody struct {
+	transport *resumeTransport
+	req       *http.Request
+	body      io.ReadCloser
+
+	// pending holds bytes of the event being received
+	pending []byte
+	// ready holds complete events not yet read
+	ready       []byte
+	lastEventID string
+	attempts    int
+	err         error
+}
+
+func (b *resumableBody) Read(p []byte) (int, error) {
+	buf := make([]byte, 32*1024)
+	for len(b.ready) == 0 && b.err == nil {
+		n, err := b.body.Read(buf)
+		b.receive(buf[:n])
+		if err == nil {
+			continue
+		}
+		if err == io.EOF {
+			// A clean end; hand over whatever is left
+			b.ready = append(b.ready, b.pending...)
+			b.pending = nil
+			b.err = io.EOF
+			break
+		}
+		if !b.resume(err) {
+			b.err = err
+		}
+	}
+
+	if len(b.ready) > 0 {
+		n := copy(p, b.ready)
+		b.ready = b.ready[n:]
+		return n, nil
+	}
+	return 0, b.err
+}
+
+// receive splits data into complete events and remembers their IDs
+func (b *resumableBody) receive(data []byte) {
+	b.pending = append(b.pending, data...)
+	for {
+		end, sep := eventEnd(b.pending)
+		if end < 0 {
+			return
+		}
+		event := b.pending[:end+sep]
+		for _, line := range strings.Split(string(event), "\n") {
+			line = strings.TrimSuffix(line, "\r")
+			if id, ok := strings.CutPrefix(line, "id:"); ok {
+				b.lastEventID = strings.TrimPrefix(id, " ")
+			}
+		}
+		b.ready = append(b.ready, event...)
+		b.pending = b.pending[end+sep:]
+	}
+}
+
+// eventEnd finds the blank line ending the first event in data
+func eventEnd(data []byte) (int, int) {
+	best, sep := -1, 0
+	for _, s := range []string{"\n\n", "\r\n\r\n", "\r\r"} {
+		if i := bytes.Index(data, []byte(s)); i >= 0 && (best < 0 || i < best) {
+			best, sep = i, len(s)
+		}
+	}
+	return best, sep
+}
+
+// resume reconnects after the stream failed with cause, dropping the partly
+// received event. It reports whether a new stream was opened.
+func (b *resumableBody) resume(cause error) bool {
+	ctx := b.req.Context()
+	if b.lastEventID == "" || ctx.Err() != nil || errors.Is(cause, http.ErrBodyReadAfterClose) {
+		return false
+	}
+
+	for b.attempts < maxResumeAttempts {
+		b.attempts++
+		select {
+		case <-time.After(time.Duration(b.attempts) * 500 * time.Millisecond):
+		case <-ctx.Done():
+			return false
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.req.URL.String(), nil)
+		if err != nil {
+			return false
+		}
+		req.Header = b.req.Header.Clone()
+		req.Header.Del("Content-Type")
+		req.Header.Del("Content-Length")
+		req.Header.Set("Accept", "text/event-stream")
+		req.Header.Set("Last-Event-ID", b.lastEventID)
+		req.Host = b.req.Host
+
+		resp, err := b.transport.base.RoundTrip(req)
+		if err != nil {
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			// The server cannot resume this stream, e.g. 405 without GET support
+			if resp.StatusCode < 500 {
+				return false
+			}
+			continue
+		}
+
+		b.body.Close()
+		b.body = resp.Body
+		b.pending = nil
+		return true
+	}
+	return false
+}
+
+func (b *resumableBody) Close() error {
+	return b.body.Close()
+}
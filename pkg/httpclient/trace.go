@@ -0,0 +1,247 @@
+package httpclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// redactedHeaders are never written to trace files
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"X-Api-Key":           true,
+	"X-Goog-Api-Key":      true,
+	"Api-Key":             true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// redactedQueryParams carry API keys in the URL for some providers
+var redactedQueryParams = []string{"key", "api_key", "api-key"}
+
+// base64Run matches long runs of base64, such as inline image data
+var base64Run = regexp.MustCompile(`[A-Za-z0-9+/]{256,}={0,2}`)
+
+const redacted = "[REDACTED]"
+
+// minSecretLength keeps short values, which cannot be secrets, from being
+// redacted everywhere they happen to appear
+const minSecretLength = 8
+
+// Tracer writes HTTP requests and responses to numbered files in a directory,
+// with credentials redacted
+type Tracer struct {
+	dir            string
+	truncateBase64 bool
+	seq            atomic.Int64
+
+	mu      sync.RWMutex
+	secrets []string
+	// headers are redacted besides redactedHeaders, see RedactHeaders
+	headers map[string]bool
+}
+
+// NewTracer creates dir if needed and returns a tracer writing to a new
+// timestamped subdirectory of it, so that runs never overwrite each other's
+// traces. Every occurrence of the given secrets is redacted, wherever it
+// appears. With truncateBase64, long base64 strings in request bodies are
+// shortened.
+func NewTracer(dir string, truncateBase64 bool, secrets ...string) (*Tracer, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("error creating trace directory: %w", err)
+	}
+	runDir, err := os.MkdirTemp(dir, time.Now().Format("20060102-150405")+"-*")
+	if err != nil {
+		return nil, fmt.Errorf("error creating trace directory: %w", err)
+	}
+	t := &Tracer{dir: runDir, truncateBase64: truncateBase64, headers: make(map[string]bool)}
+	t.AddSecrets(secrets...)
+	return t, nil
+}
+
+// AddSecrets redacts every occurrence of the given secrets from now on
+func (t *Tracer) AddSecrets(secrets ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, secret := range secrets {
+		if len(secret) >= minSecretLength && !slices.Contains(t.secrets, secret) {
+			t.secrets = append(t.secrets, secret)
+		}
+	}
+}
+
+// RedactHeaders redacts the values of the named headers from now on
+func (t *Tracer) RedactHeaders(names ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, name := range names {
+		t.headers[http.CanonicalHeaderKey(name)] = true
+	}
+}
+
+// Dir returns the directory this run's traces are written to
+func (t *Tracer) Dir() string {
+	return t.dir
+}
+
+// Client returns a copy of client whose requests are traced. A nil client
+// is treated as http.DefaultClient.
+func (t *Tracer) Client(client *http.Client) *http.Client {
+	traced := &http.Client{}
+	if client != nil {
+		*traced = *client
+	}
+	traced.Transport = t.Wrap(traced.Transport)
+	return traced
+}
+
+// Wrap returns a RoundTripper that traces requests sent through base. A nil
+// base uses http.DefaultTransport.
+func (t *Tracer) Wrap(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &traceTransport{tracer: t, base: base}
+}
+
+type traceTransport struct {
+	tracer *Tracer
+	base   http.RoundTripper
+}
+
+func (tt *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t := tt.tracer
+	prefix := filepath.Join(t.dir, fmt.Sprintf("%04d", t.seq.Add(1)))
+
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	t.writeRequest(prefix+"-request.txt", req, body)
+
+	resp, err := tt.base.RoundTrip(req)
+	if err != nil {
+		t.writeFile(prefix+"-response.txt", []byte(fmt.Sprintf("error: %v\n", err)))
+		return nil, err
+	}
+
+	f, err := os.OpenFile(prefix+"-response.txt", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return resp, nil
+	}
+	var head bytes.Buffer
+	fmt.Fprintf(&head, "%s %s\n", resp.Proto, resp.Status)
+	t.writeHeaders(&head, resp.Header)
+	head.WriteString("\n")
+	f.Write(head.Bytes())
+
+	// Streamed responses are written as they are read, so the file also
+	// shows how far a stream got before failing
+	resp.Body = &traceBody{ReadCloser: resp.Body, file: f, tracer: t}
+	return resp, nil
+}
+
+func (t *Tracer) writeRequest(path string, req *http.Request, body []byte) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s %s\n", req.Method, t.redactURL(req), req.Proto)
+	t.writeHeaders(&buf, req.Header)
+	buf.WriteString("\n")
+	if t.truncateBase64 {
+		body = base64Run.ReplaceAllFunc(body, func(run []byte) []byte {
+			return []byte(fmt.Sprintf("%s...[%d bytes truncated]", run[:32], len(run)-32))
+		})
+	}
+	buf.Write(t.redact(body))
+	t.writeFile(path, buf.Bytes())
+}
+
+func (t *Tracer) writeHeaders(buf *bytes.Buffer, header http.Header) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		hidden := redactedHeaders[http.CanonicalHeaderKey(name)]
+		if !hidden {
+			t.mu.RLock()
+			hidden = t.headers[http.CanonicalHeaderKey(name)]
+			t.mu.RUnlock()
+		}
+		for _, value := range header[name] {
+			if hidden {
+				value = redacted
+			}
+			fmt.Fprintf(buf, "%s: %s\n", name, t.redact([]byte(value)))
+		}
+	}
+}
+
+func (t *Tracer) redactURL(req *http.Request) string {
+	u := *req.URL
+	query := u.Query()
+	changed := false
+	for _, param := range redactedQueryParams {
+		if query.Has(param) {
+			query.Set(param, redacted)
+			changed = true
+		}
+	}
+	if changed {
+		u.RawQuery = query.Encode()
+	}
+	u.User = nil
+	return string(t.redact([]byte(u.String())))
+}
+
+func (t *Tracer) redact(data []byte) []byte {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for _, secret := range t.secrets {
+		data = bytes.ReplaceAll(data, []byte(secret), []byte(redacted))
+	}
+	return data
+}
+
+func (t *Tracer) writeFile(path string, data []byte) {
+	_ = os.WriteFile(path, data, 0600)
+}
+
+// traceBody copies a response body to the trace file as it is read
+type traceBody struct {
+	io.ReadCloser
+	file   *os.File
+	tracer *Tracer
+}
+
+func (b *traceBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.file.Write(b.tracer.redact(p[:n]))
+	}
+	if err != nil && err != io.EOF {
+		fmt.Fprintf(b.file, "\n[read error: %v]\n", err)
+	}
+	return n, err
+}
+
+func (b *traceBody) Close() error {
+	b.file.Close()
+	return b.ReadCloser.Close()
+}
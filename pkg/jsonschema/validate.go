@@ -0,0 +1,56 @@
+// Package jsonschema validates decoded JSON values against JSON Schemas
+// given as decoded JSON, as model output and form schemas are. The work is
+// done by github.com/google/jsonschema-go, which supports draft-07 and
+// draft 2020-12.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// Check reports whether schema is a JSON Schema that values can be
+// validated against
+func Check(schema map[string]interface{}) error {
+	_, err := resolve(schema)
+	return err
+}
+
+// Validate checks value against schema. Values are expected in the shape
+// produced by encoding/json, i.e. map[string]interface{}, []interface{},
+// float64, string, bool and nil.
+func Validate(schema map[string]interface{}, value interface{}) error {
+	resolved, err := resolve(schema)
+	if err != nil {
+		return err
+	}
+	return resolved.Validate(value)
+}
+
+// ValidateJSON parses data and validates it against schema
+func ValidateJSON(schema map[string]interface{}, data []byte) (interface{}, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return value, Validate(schema, value)
+}
+
+// resolve converts schema to the library's form and resolves its $refs
+func resolve(schema map[string]interface{}) (*jsonschema.Resolved, error) {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	var s jsonschema.Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	resolved, err := s.Resolve(nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return resolved, nil
+}
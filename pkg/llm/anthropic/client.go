@@ -15,7 +15,11 @@ type Client struct {
 	baseURL string
 }
 
-func NewClient(apiKey string, baseURL string) *Client {
+// NewClient creates an API client. A nil httpClient uses http.DefaultClient.
+func NewClient(apiKey string, baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
 	if baseURL == "" {
 		baseURL = "https://api.anthropic.com/v1"
 	} else if !strings.HasSuffix(baseURL, "/v1") {
@@ -24,19 +28,35 @@ func NewClient(apiKey string, baseURL string) *Client {
 	return &Client{
 		apiKey:  apiKey,
 		baseURL: baseURL,
-		client:  &http.Client{},
+		client:  httpClient,
 	}
 }
 
 func (c *Client) CreateMessage(ctx context.Context, req CreateRequest) (*APIMessage, error) {
+	var message APIMessage
+	if err := c.post(ctx, "messages", req, &message); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+func (c *Client) CountTokens(ctx context.Context, req CountTokensRequest) (*CountTokensResponse, error) {
+	var count CountTokensResponse
+	if err := c.post(ctx, "messages/count_tokens", req, &count); err != nil {
+		return nil, err
+	}
+	return &count, nil
+}
+
+func (c *Client) post(ctx context.Context, path string, req interface{}, out interface{}) error {
 	body, err := json.Marshal(req)
 	if err != nil {
-		return nil, fmt.Errorf("error marshaling request: %w", err)
+		return fmt.Errorf("error marshaling request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/messages", c.baseURL), bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/%s", c.baseURL, path), bytes.NewReader(body))
 	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
+		return fmt.Errorf("error creating request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
@@ -45,7 +65,7 @@ func (c *Client) CreateMessage(ctx context.Context, req CreateRequest) (*APIMess
 
 	resp, err := c.client.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
+		return fmt.Errorf("error making request: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -57,20 +77,19 @@ func (c *Client) CreateMessage(ctx context.Context, req CreateRequest) (*APIMess
 			} `json:"error"`
 		}
 		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
-			return nil, fmt.Errorf("error response with status %d", resp.StatusCode)
+			return fmt.Errorf("error response with status %d", resp.StatusCode)
 		}
 
 		if errResp.Error.Type == "overloaded_error" {
-			return nil, fmt.Errorf("overloaded_error: %s", errResp.Error.Message)
+			return fmt.Errorf("overloaded_error: %s", errResp.Error.Message)
 		}
 
-		return nil, fmt.Errorf("%s: %s", errResp.Error.Type, errResp.Error.Message)
+		return fmt.Errorf("%s: %s", errResp.Error.Type, errResp.Error.Message)
 	}
 
-	var message APIMessage
-	if err := json.NewDecoder(resp.Body).Decode(&message); err != nil {
-		return nil, fmt.Errorf("error decoding response: %w", err)
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("error decoding response: %w", err)
 	}
 
-	return &message, nil
+	return nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/charmbracelet/log"
@@ -17,12 +18,12 @@ type Provider struct {
 	systemPrompt string
 }
 
-func NewProvider(apiKey, baseURL, model, systemPrompt string) *Provider {
+func NewProvider(apiKey, baseURL, model, systemPrompt string, httpClient *http.Client) *Provider {
 	if model == "" {
 		model = "claude-3-5-sonnet-20240620" // 默认模型
 	}
 	return &Provider{
-		client:       NewClient(apiKey, baseURL),
+		client:       NewClient(apiKey, baseURL, httpClient),
 		model:        model,
 		systemPrompt: systemPrompt,
 	}
@@ -33,12 +34,82 @@ func (p *Provider) CreateMessage(
 	prompt string,
 	messages []llm.Message,
 	tools []llm.Tool,
+	opts ...llm.RequestOption,
 ) (llm.Message, error) {
+	options := llm.NewRequestOptions(opts...)
 	log.Debug("creating message",
 		"prompt", prompt,
 		"num_messages", len(messages),
 		"num_tools", len(tools))
 
+	anthropicMessages := convertMessages(prompt, messages)
+	anthropicTools := convertTools(tools)
+
+	var toolChoice *ToolChoice
+	if options.ToolChoice != nil && len(tools) > 0 {
+		toolChoice = convertToolChoice(*options.ToolChoice)
+	}
+
+	// Structured output is implemented as a tool the model has to call. When
+	// other tools are available the model may call any of them, and the answer
+	// is whatever it passes to the output tool in the end.
+	if options.OutputSchema != nil {
+		if toolChoice != nil && toolChoice.Type == "none" {
+			anthropicTools = nil
+		}
+		anthropicTools = append(anthropicTools, convertTools([]llm.Tool{llm.OutputTool(options.OutputSchema)})...)
+		if len(anthropicTools) == 1 {
+			toolChoice = &ToolChoice{Type: "tool", Name: llm.OutputToolName}
+		} else if toolChoice == nil || toolChoice.Type == "auto" {
+			toolChoice = &ToolChoice{Type: "any"}
+		}
+	}
+
+	log.Debug("sending messages to Anthropic",
+		"messages", anthropicMessages,
+		"num_tools", len(tools))
+
+	maxTokens := llm.DefaultMaxTokens
+	if options.MaxTokens > 0 {
+		maxTokens = options.MaxTokens
+	}
+
+	// Make the API call
+	resp, err := p.client.CreateMessage(ctx, CreateRequest{
+		Model:      p.model,
+		Messages:   anthropicMessages,
+		MaxTokens:  maxTokens,
+		Tools:      anthropicTools,
+		ToolChoice: toolChoice,
+		System:     p.systemPrompt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Message{Msg: *resp}, nil
+}
+
+// CountTokens uses the count_tokens endpoint to get the exact number of input
+// tokens the request would use
+func (p *Provider) CountTokens(
+	ctx context.Context,
+	messages []llm.Message,
+	tools []llm.Tool,
+) (int, error) {
+	resp, err := p.client.CountTokens(ctx, CountTokensRequest{
+		Model:    p.model,
+		Messages: convertMessages("", messages),
+		Tools:    convertTools(tools),
+		System:   p.systemPrompt,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return resp.InputTokens, nil
+}
+
+func convertMessages(prompt string, messages []llm.Message) []MessageParam {
 	anthropicMessages := make([]MessageParam, 0, len(messages))
 
 	for _, msg := range messages {
@@ -113,7 +184,24 @@ func (p *Provider) CreateMessage(
 		})
 	}
 
-	// Convert tools to Anthropic format
+	return anthropicMessages
+}
+
+func convertToolChoice(choice llm.ToolChoice) *ToolChoice {
+	switch choice.Mode {
+	case llm.ToolChoiceNone:
+		return &ToolChoice{Type: "none"}
+	case llm.ToolChoiceRequired:
+		return &ToolChoice{Type: "any"}
+	case llm.ToolChoiceTool:
+		return &ToolChoice{Type: "tool", Name: choice.Name}
+	default:
+		return &ToolChoice{Type: "auto"}
+	}
+}
+
+// convertTools converts tools to Anthropic format
+func convertTools(tools []llm.Tool) []Tool {
 	anthropicTools := make([]Tool, len(tools))
 	for i, tool := range tools {
 		anthropicTools[i] = Tool{
@@ -123,31 +211,20 @@ func (p *Provider) CreateMessage(
 				Type:       tool.InputSchema.Type,
 				Properties: tool.InputSchema.Properties,
 				Required:   tool.InputSchema.Required,
+				Extra:      tool.InputSchema.Extra,
 			},
 		}
 	}
-
-	log.Debug("sending messages to Anthropic",
-		"messages", anthropicMessages,
-		"num_tools", len(tools))
-
-	// Make the API call
-	resp, err := p.client.CreateMessage(ctx, CreateRequest{
-		Model:     p.model,
-		Messages:  anthropicMessages,
-		MaxTokens: 4096,
-		Tools:     anthropicTools,
-		System:    p.systemPrompt,
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	return &Message{Msg: *resp}, nil
+	return anthropicTools
 }
 
-func (p *Provider) SupportsTools() bool {
-	return true
+func (p *Provider) Capabilities() llm.Capabilities {
+	caps := llm.ModelCapabilities("anthropic", p.model)
+	caps.Tools = true
+	caps.ParallelToolCalls = true
+	caps.SystemPrompt = true
+	caps.DefaultOutputTokens = llm.DefaultMaxTokens
+	return caps
 }
 
 func (p *Provider) Name() string {
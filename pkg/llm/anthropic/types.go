@@ -10,11 +10,28 @@ import (
 )
 
 type CreateRequest struct {
-	Model     string         `json:"model"`
-	Messages  []MessageParam `json:"messages"`
-	MaxTokens int            `json:"max_tokens"`
-	System    string         `json:"system,omitempty"`
-	Tools     []Tool         `json:"tools,omitempty"`
+	Model      string         `json:"model"`
+	Messages   []MessageParam `json:"messages"`
+	MaxTokens  int            `json:"max_tokens"`
+	System     string         `json:"system,omitempty"`
+	Tools      []Tool         `json:"tools,omitempty"`
+	ToolChoice *ToolChoice    `json:"tool_choice,omitempty"`
+}
+
+type ToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+type CountTokensRequest struct {
+	Model    string         `json:"model"`
+	Messages []MessageParam `json:"messages"`
+	System   string         `json:"system,omitempty"`
+	Tools    []Tool         `json:"tools,omitempty"`
+}
+
+type CountTokensResponse struct {
+	InputTokens int `json:"input_tokens"`
 }
 
 type MessageParam struct {
@@ -42,6 +59,22 @@ type InputSchema struct {
 	Type       string                 `json:"type"`
 	Properties map[string]interface{} `json:"properties"`
 	Required   []string               `json:"required,omitempty"`
+	// Extra holds the schema's other keywords, see llm.Schema
+	Extra map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON writes Extra alongside the other keywords
+func (s InputSchema) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(s.Extra)+3)
+	for key, value := range s.Extra {
+		m[key] = value
+	}
+	m["type"] = s.Type
+	m["properties"] = s.Properties
+	if len(s.Required) > 0 {
+		m["required"] = s.Required
+	}
+	return json.Marshal(m)
 }
 
 type APIMessage struct {
@@ -155,6 +188,21 @@ func (m *Message) GetUsage() (input int, output int) {
 	return m.Msg.Usage.InputTokens, m.Msg.Usage.OutputTokens
 }
 
+func (m *Message) GetStopReason() llm.StopReason {
+	if m.Msg.StopReason == nil {
+		return llm.StopReasonUnknown
+	}
+	switch *m.Msg.StopReason {
+	case "max_tokens":
+		return llm.StopReasonMaxTokens
+	case "tool_use":
+		return llm.StopReasonToolUse
+	case "end_turn", "stop_sequence":
+		return llm.StopReasonEnd
+	}
+	return llm.StopReasonUnknown
+}
+
 // ToolCall implements the llm.ToolCall interface
 type ToolCall struct {
 	id   string
@@ -0,0 +1,117 @@
+package llm
+
+import "strings"
+
+// defaultContextWindow is used for models we know nothing about
+const defaultContextWindow = 8192
+
+// Capabilities describes what a provider and model support. It does not
+// change during a session: most providers derive it from the model name on
+// every call, which is cheap, and Ollama asks its server once and caches
+// the answer.
+type Capabilities struct {
+	// Tools is whether the model can call tools
+	Tools bool
+	// ParallelToolCalls is whether the model can call several tools at once
+	ParallelToolCalls bool
+	// Vision is whether the model accepts images
+	Vision bool
+	// Streaming is whether the provider streams responses
+	Streaming bool
+	// SystemPrompt is whether the model accepts a system prompt
+	SystemPrompt bool
+	// JSONSchemaOutput is whether the API can constrain the answer to a JSON Schema
+	JSONSchemaOutput bool
+	// Thinking is whether the model can reason before answering
+	Thinking bool
+	// ContextWindow is the number of tokens the model accepts, input and output together
+	ContextWindow int
+	// MaxOutputTokens is the largest output token limit, zero if unknown
+	MaxOutputTokens int
+	// DefaultOutputTokens is the output token limit of requests that do not
+	// set one, zero if unknown
+	DefaultOutputTokens int
+	// ContextWindowGuessed is whether ContextWindow is the default for a
+	// model we know nothing about
+	ContextWindowGuessed bool
+}
+
+// modelInfo holds what we know about a family of models
+type modelInfo struct {
+	contextWindow   int
+	maxOutputTokens int
+	vision          bool
+	thinking        bool
+}
+
+// knownModels maps model name prefixes to what the models support. A prefix
+// matches model names that equal it or continue with a dash, so "gpt-4"
+// matches gpt-4-0613 but not gpt-4o or gpt-4.5. Longer prefixes are matched
+// first.
+var knownModels = map[string]map[string]modelInfo{
+	"anthropic": {
+		"claude-3-7":      {200000, 64000, true, true},
+		"claude-3-5":      {200000, 8192, true, false},
+		"claude-3":        {200000, 4096, true, false},
+		"claude-sonnet-4": {200000, 64000, true, true},
+		"claude-opus-4":   {200000, 32000, true, true},
+		"claude-haiku-4":  {200000, 64000, true, true},
+		"claude-2":        {100000, 4096, false, false},
+		"claude-instant":  {100000, 4096, false, false},
+		// Newer Claude models all accept images and 200k tokens
+		"claude": {200000, 8192, true, false},
+	},
+	"openai": {
+		"gpt-5":                {400000, 128000, true, true},
+		"gpt-4.5":              {128000, 16384, true, false},
+		"gpt-4.1":              {1047576, 32768, true, false},
+		"gpt-4o":               {128000, 16384, true, false},
+		"gpt-4-turbo":          {128000, 4096, true, false},
+		"gpt-4-1106-preview":   {128000, 4096, false, false},
+		"gpt-4-0125-preview":   {128000, 4096, false, false},
+		"gpt-4-vision-preview": {128000, 4096, true, false},
+		"gpt-4-32k":            {32768, 4096, false, false},
+		"gpt-4":                {8192, 4096, false, false},
+		"gpt-3.5-turbo":        {16385, 4096, false, false},
+		"o1":                   {200000, 100000, true, true},
+		"o3":                   {200000, 100000, true, true},
+		"o4":                   {200000, 100000, true, true},
+	},
+	"google": {
+		"gemini-2.5":       {1048576, 65536, true, true},
+		"gemini-2.0":       {1048576, 8192, true, false},
+		"gemini-1.5-pro":   {2097152, 8192, true, false},
+		"gemini-1.5-flash": {1048576, 8192, true, false},
+		"gemini-1.0":       {32760, 2048, false, false},
+	},
+}
+
+// ModelCapabilities returns the context window, output limit, vision and
+// thinking support known for a model. Unknown models get a conservative
+// context window and are assumed to accept images, so that images are not
+// dropped silently; providers fill in the remaining fields.
+func ModelCapabilities(provider, model string) Capabilities {
+	caps := Capabilities{
+		ContextWindow:        defaultContextWindow,
+		ContextWindowGuessed: true,
+		Vision:               true,
+	}
+
+	best, bestLen := modelInfo{}, -1
+	for prefix, info := range knownModels[strings.ToLower(provider)] {
+		matches := model == prefix || strings.HasPrefix(model, prefix+"-")
+		if matches && len(prefix) > bestLen {
+			best, bestLen = info, len(prefix)
+		}
+	}
+	if bestLen < 0 {
+		return caps
+	}
+
+	caps.ContextWindow = best.contextWindow
+	caps.ContextWindowGuessed = false
+	caps.MaxOutputTokens = best.maxOutputTokens
+	caps.Vision = best.vision
+	caps.Thinking = best.thinking
+	return caps
+}
@@ -2,7 +2,9 @@ package google
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/google/generative-ai-go/genai"
@@ -16,11 +18,24 @@ type Provider struct {
 	model  *genai.GenerativeModel
 	chat   *genai.ChatSession
 
+	modelName string
+
 	toolCallID int
 }
 
-func NewProvider(ctx context.Context, apiKey, model, systemPrompt string) (*Provider, error) {
-	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+// NewProvider creates a new Gemini provider. A nil httpClient uses the
+// default transport.
+func NewProvider(ctx context.Context, apiKey, model, systemPrompt string, httpClient *http.Client) (*Provider, error) {
+	opts := []option.ClientOption{option.WithAPIKey(apiKey)}
+	if httpClient != nil {
+		// The API key option is ignored when a client is given, so the
+		// transport has to add the key itself
+		opts = []option.ClientOption{option.WithHTTPClient(&http.Client{
+			Transport: &apiKeyTransport{apiKey: apiKey, base: httpClient.Transport},
+			Timeout:   httpClient.Timeout,
+		})}
+	}
+	client, err := genai.NewClient(ctx, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -33,10 +48,101 @@ func NewProvider(ctx context.Context, apiKey, model, systemPrompt string) (*Prov
 		client: client,
 		model:  m,
 		chat:   m.StartChat(),
+
+		modelName: model,
 	}, nil
 }
 
-func (p *Provider) CreateMessage(ctx context.Context, prompt string, messages []llm.Message, tools []llm.Tool) (llm.Message, error) {
+// apiKeyTransport adds the Gemini API key header to every request
+type apiKeyTransport struct {
+	apiKey string
+	base   http.RoundTripper
+}
+
+func (t *apiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("x-goog-api-key", t.apiKey)
+	return base.RoundTrip(req)
+}
+
+func (p *Provider) CreateMessage(ctx context.Context, prompt string, messages []llm.Message, tools []llm.Tool, opts ...llm.RequestOption) (llm.Message, error) {
+	options := llm.NewRequestOptions(opts...)
+	p.chat.History = convertHistory(messages)
+	p.setTools(tools)
+
+	p.model.ToolConfig = nil
+	if options.ToolChoice != nil && len(tools) > 0 {
+		p.model.ToolConfig = convertToolChoice(*options.ToolChoice)
+	}
+
+	// Gemini rejects a response schema combined with function calling, so the
+	// schema only applies to requests without tools
+	p.model.ResponseMIMEType = ""
+	p.model.ResponseSchema = nil
+	if options.OutputSchema != nil && len(tools) == 0 {
+		p.model.ResponseMIMEType = "application/json"
+		p.model.ResponseSchema = propertyToGoogleSchema(options.OutputSchema)
+	}
+
+	p.model.MaxOutputTokens = nil
+	if options.MaxTokens > 0 {
+		p.model.SetMaxOutputTokens(int32(options.MaxTokens))
+	}
+
+	// The provided messages slice (and thus history) already includes the new prompt,
+	// so we just call SendMessage with an empty string that will be trimmed by the server.
+	resp, err := p.chat.SendMessage(ctx, genai.Text(""))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Candidates) == 0 {
+		return nil, fmt.Errorf("no response from model")
+	}
+
+	// The library enforces a generation config with 1 candidate.
+	m := &Message{
+		Candidate:  resp.Candidates[0],
+		toolCallID: p.toolCallID,
+	}
+
+	p.toolCallID += len(m.Candidate.FunctionCalls())
+	return m, nil
+}
+
+// CountTokens asks the API for the number of input tokens. The endpoint only
+// accepts a single turn, so the history is flattened into text parts.
+func (p *Provider) CountTokens(ctx context.Context, messages []llm.Message, tools []llm.Tool) (int, error) {
+	p.setTools(tools)
+
+	var parts []genai.Part
+	for _, content := range convertHistory(messages) {
+		for _, part := range content.Parts {
+			switch v := part.(type) {
+			case genai.Text:
+				parts = append(parts, v)
+			default:
+				raw, _ := json.Marshal(v)
+				parts = append(parts, genai.Text(raw))
+			}
+		}
+	}
+	if len(parts) == 0 {
+		return 0, nil
+	}
+
+	resp, err := p.model.CountTokens(ctx, parts...)
+	if err != nil {
+		return 0, err
+	}
+	return int(resp.TotalTokens), nil
+}
+
+func convertHistory(messages []llm.Message) []*genai.Content {
 	var hist []*genai.Content
 	for _, msg := range messages {
 		for _, call := range msg.GetToolCalls() {
@@ -72,6 +178,24 @@ func (p *Provider) CreateMessage(ctx context.Context, prompt string, messages []
 		}
 	}
 
+	return hist
+}
+
+func convertToolChoice(choice llm.ToolChoice) *genai.ToolConfig {
+	config := &genai.FunctionCallingConfig{Mode: genai.FunctionCallingAuto}
+	switch choice.Mode {
+	case llm.ToolChoiceNone:
+		config.Mode = genai.FunctionCallingNone
+	case llm.ToolChoiceRequired:
+		config.Mode = genai.FunctionCallingAny
+	case llm.ToolChoiceTool:
+		config.Mode = genai.FunctionCallingAny
+		config.AllowedFunctionNames = []string{choice.Name}
+	}
+	return &genai.ToolConfig{FunctionCallingConfig: config}
+}
+
+func (p *Provider) setTools(tools []llm.Tool) {
 	p.model.Tools = nil
 	for _, tool := range tools {
 		p.model.Tools = append(p.model.Tools, &genai.Tool{
@@ -84,27 +208,6 @@ func (p *Provider) CreateMessage(ctx context.Context, prompt string, messages []
 			},
 		})
 	}
-
-	p.chat.History = hist
-	// The provided messages slice (and thus history) already includes the new prompt,
-	// so we just call SendMessage with an empty string that will be trimmed by the server.
-	resp, err := p.chat.SendMessage(ctx, genai.Text(""))
-	if err != nil {
-		return nil, err
-	}
-
-	if len(resp.Candidates) == 0 {
-		return nil, fmt.Errorf("no response from model")
-	}
-
-	// The library enforces a generation config with 1 candidate.
-	m := &Message{
-		Candidate:  resp.Candidates[0],
-		toolCallID: p.toolCallID,
-	}
-
-	p.toolCallID += len(m.Candidate.FunctionCalls())
-	return m, nil
 }
 
 func (p *Provider) CreateToolResponse(toolCallID string, content any) (llm.Message, error) {
@@ -112,9 +215,15 @@ func (p *Provider) CreateToolResponse(toolCallID string, content any) (llm.Messa
 	return nil, nil
 }
 
-func (p *Provider) SupportsTools() bool {
-	// UNUSED: Nothing in root.go calls this.
-	return true
+func (p *Provider) Capabilities() llm.Capabilities {
+	caps := llm.ModelCapabilities("google", p.modelName)
+	caps.Tools = true
+	caps.ParallelToolCalls = true
+	caps.SystemPrompt = true
+	// Without a limit the API allows the model's largest output
+	caps.DefaultOutputTokens = caps.MaxOutputTokens
+	caps.JSONSchemaOutput = true
+	return caps
 }
 
 func (p *Provider) Name() string {
@@ -159,16 +268,37 @@ func propertyToGoogleSchema(properties map[string]any) *genai.Schema {
 		s.Description = desc
 	}
 
+	if enum, ok := properties["enum"].([]any); ok {
+		for _, e := range enum {
+			if str, ok := e.(string); ok {
+				s.Enum = append(s.Enum, str)
+			}
+		}
+		if len(s.Enum) > 0 {
+			s.Format = "enum"
+		}
+	}
+
 	// Objects and arrays need to have their properties recursively mapped.
 	if s.Type == genai.TypeObject {
-		objectProperties := properties["properties"].(map[string]any)
+		objectProperties, _ := properties["properties"].(map[string]any)
 		s.Properties = make(map[string]*genai.Schema)
 		for name, prop := range objectProperties {
-			s.Properties[name] = propertyToGoogleSchema(prop.(map[string]any))
+			if m, ok := prop.(map[string]any); ok {
+				s.Properties[name] = propertyToGoogleSchema(m)
+			}
+		}
+		if required, ok := properties["required"].([]any); ok {
+			for _, r := range required {
+				if str, ok := r.(string); ok {
+					s.Required = append(s.Required, str)
+				}
+			}
 		}
 	} else if s.Type == genai.TypeArray {
-		itemProperties := properties["items"].(map[string]any)
-		s.Items = propertyToGoogleSchema(itemProperties)
+		if itemProperties, ok := properties["items"].(map[string]any); ok {
+			s.Items = propertyToGoogleSchema(itemProperties)
+		}
 	}
 
 	return s
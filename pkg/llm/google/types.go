@@ -70,3 +70,16 @@ func (m *Message) GetToolResponseID() string {
 func (m *Message) GetUsage() (input int, output int) {
 	return 0, 0
 }
+
+func (m *Message) GetStopReason() llm.StopReason {
+	switch m.Candidate.FinishReason {
+	case genai.FinishReasonMaxTokens:
+		return llm.StopReasonMaxTokens
+	case genai.FinishReasonStop:
+		if len(m.Candidate.FunctionCalls()) > 0 {
+			return llm.StopReasonToolUse
+		}
+		return llm.StopReasonEnd
+	}
+	return llm.StopReasonUnknown
+}
@@ -4,38 +4,76 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/ollama/ollama/api"
+	"github.com/ollama/ollama/envconfig"
 
 	"github.com/mark3labs/mcphost/pkg/history"
 	"github.com/mark3labs/mcphost/pkg/llm"
 )
 
+// defaultNumCtx is Ollama's own default context size
+const defaultNumCtx = 2048
+
+// showTimeout bounds the request for model details
+const showTimeout = 10 * time.Second
+
 func boolPtr(b bool) *bool {
 	return &b
 }
 
+// Config holds the Ollama specific settings from the "providers.ollama"
+// section of the config file
+type Config struct {
+	// Options are sent with every request, e.g. num_ctx, num_predict or temperature
+	Options map[string]interface{} `json:"options,omitempty"`
+	// KeepAlive is how long the model stays loaded after a request, e.g. "10m".
+	// A negative value keeps it loaded until Ollama exits.
+	KeepAlive *api.Duration `json:"keepAlive,omitempty"`
+	// AutoPull pulls missing models without asking for confirmation
+	AutoPull bool `json:"autoPull,omitempty"`
+}
+
 // Provider implements the Provider interface for Ollama
 type Provider struct {
 	client       *api.Client
 	model        string
 	systemPrompt string
+	options      map[string]interface{}
+	keepAlive    *api.Duration
+
+	capsOnce sync.Once
+	caps     llm.Capabilities
 }
 
-// NewProvider creates a new Ollama provider
-func NewProvider(model string, systemPrompt string) (*Provider, error) {
-	client, err := api.ClientFromEnvironment()
-	if err != nil {
-		return nil, err
+// NewProvider creates a new Ollama provider. The server address is read from
+// OLLAMA_HOST; a nil httpClient uses http.DefaultClient.
+func NewProvider(model string, systemPrompt string, cfg Config, httpClient *http.Client) (*Provider, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	client := api.NewClient(envconfig.Host(), httpClient)
+
+	// Catch options of the wrong type here rather than on the first request
+	var options api.Options
+	if err := options.FromMap(cfg.Options); err != nil {
+		return nil, fmt.Errorf("invalid Ollama options: %w", err)
 	}
+
 	return &Provider{
 		client:       client,
 		model:        model,
 		systemPrompt: systemPrompt,
+		options:      cfg.Options,
+		keepAlive:    cfg.KeepAlive,
 	}, nil
 }
 
@@ -44,7 +82,18 @@ func (p *Provider) CreateMessage(
 	prompt string,
 	messages []llm.Message,
 	tools []llm.Tool,
+	opts ...llm.RequestOption,
 ) (llm.Message, error) {
+	options := llm.NewRequestOptions(opts...)
+	if options.ToolChoice != nil {
+		if err := p.ValidateToolChoice(*options.ToolChoice); err != nil {
+			return nil, err
+		}
+		// Ollama has no tool choice parameter, but not sending tools has the same effect
+		if options.ToolChoice.Mode == llm.ToolChoiceNone {
+			tools = nil
+		}
+	}
 	log.Debug("creating message",
 		"prompt", prompt,
 		"num_messages", len(messages),
@@ -71,18 +120,35 @@ func (p *Provider) CreateMessage(
 			// Handle HistoryMessage format
 			if historyMsg, ok := msg.(*history.HistoryMessage); ok {
 				for _, block := range historyMsg.Content {
-					for _, c := range block.Content.([]mcp.Content) {
-						if image, ok := c.(mcp.ImageContent); ok {
-							// ImageContent returned from tool is base64-encoded
-							imageDataRaw, err := base64.StdEncoding.DecodeString(image.Data)
-							if err != nil {
-								continue
+					var texts []string
+					switch blockContent := block.Content.(type) {
+					case []mcp.Content:
+						for _, c := range blockContent {
+							if image, ok := c.(mcp.ImageContent); ok {
+								// ImageContent returned from tool is base64-encoded
+								imageDataRaw, err := base64.StdEncoding.DecodeString(image.Data)
+								if err != nil {
+									continue
+								}
+								imageContent = append(imageContent, api.ImageData(imageDataRaw))
+							}
+						}
+					case string:
+						texts = append(texts, blockContent)
+					case []history.ContentBlock:
+						// Errors, denials and cancellations carry their message
+						// as text blocks
+						for _, c := range blockContent {
+							if c.Type == "text" {
+								texts = append(texts, c.Text)
 							}
-							imageContent = append(imageContent, api.ImageData(imageDataRaw))
 						}
 					}
 					if block.Type == "tool_result" {
 						content = block.Text
+						if content == "" {
+							content = strings.Join(texts, "\n")
+						}
 						break
 					}
 				}
@@ -180,14 +246,37 @@ func (p *Provider) CreateMessage(
 		"messages", ollamaMessages,
 		"num_tools", len(tools))
 
+	var format json.RawMessage
+	if options.OutputSchema != nil {
+		schema, err := json.Marshal(options.OutputSchema)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling output schema: %w", err)
+		}
+		format = schema
+	}
+
+	requestOptions := p.options
+	if options.MaxTokens > 0 {
+		requestOptions = make(map[string]interface{}, len(p.options)+1)
+		for k, v := range p.options {
+			requestOptions[k] = v
+		}
+		requestOptions["num_predict"] = options.MaxTokens
+	}
+
+	var doneReason string
 	err := p.client.Chat(ctx, &api.ChatRequest{
-		Model:    p.model,
-		Messages: ollamaMessages,
-		Tools:    ollamaTools,
-		Stream:   boolPtr(false),
+		Model:     p.model,
+		Messages:  ollamaMessages,
+		Tools:     ollamaTools,
+		Format:    format,
+		Stream:    boolPtr(false),
+		KeepAlive: p.keepAlive,
+		Options:   requestOptions,
 	}, func(r api.ChatResponse) error {
 		if r.Done {
 			response = r.Message
+			doneReason = r.DoneReason
 		}
 		return nil
 	})
@@ -196,18 +285,108 @@ func (p *Provider) CreateMessage(
 		return nil, err
 	}
 
-	return &OllamaMessage{Message: response}, nil
+	return &OllamaMessage{Message: response, DoneReason: doneReason}, nil
 }
 
-func (p *Provider) SupportsTools() bool {
-	// Check if model supports function calling
-	resp, err := p.client.Show(context.Background(), &api.ShowRequest{
-		Model: p.model,
+// Capabilities asks the Ollama server about the model the first time it is
+// called and caches the answer
+func (p *Provider) Capabilities() llm.Capabilities {
+	p.capsOnce.Do(func() {
+		p.caps = p.loadCapabilities()
 	})
+	return p.caps
+}
+
+func (p *Provider) loadCapabilities() llm.Capabilities {
+	caps := llm.Capabilities{
+		SystemPrompt:     true,
+		JSONSchemaOutput: true,
+		// Ollama only uses num_ctx tokens, whatever the model supports
+		ContextWindow: defaultNumCtx,
+	}
+	if numPredict := p.intOption("num_predict"); numPredict > 0 {
+		caps.MaxOutputTokens = numPredict
+		caps.DefaultOutputTokens = numPredict
+	}
+	if numCtx := p.intOption("num_ctx"); numCtx > 0 {
+		caps.ContextWindow = numCtx
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), showTimeout)
+	defer cancel()
+	resp, err := p.client.Show(ctx, &api.ShowRequest{Model: p.model})
 	if err != nil {
-		return false
+		// Without the details tools are offered anyway, Ollama reports an
+		// error if the model cannot use them
+		log.Warn("Failed to get model details, assuming the model supports tools",
+			"model", p.model, "error", err)
+		caps.Tools = true
+		return caps
+	}
+
+	caps.Tools = strings.Contains(resp.Template, ".Tools") ||
+		strings.Contains(resp.Modelfile, "<tools>")
+	caps.Vision = len(resp.ProjectorInfo) > 0
+	for _, family := range resp.Details.Families {
+		if family == "clip" || family == "mllama" {
+			caps.Vision = true
+		}
+	}
+	return caps
+}
+
+// ValidateToolChoice rejects tool choices that force a tool call, which the
+// Ollama API has no way to express
+func (p *Provider) ValidateToolChoice(choice llm.ToolChoice) error {
+	switch choice.Mode {
+	case llm.ToolChoiceRequired, llm.ToolChoiceTool:
+		return fmt.Errorf("ollama cannot force a tool call (tool choice %q): %w", choice, llm.ErrToolChoiceUnsupported)
+	}
+	return nil
+}
+
+// intOption returns a numeric option, or zero if it is not set
+func (p *Provider) intOption(name string) int {
+	switch n := p.options[name].(type) {
+	case float64:
+		return int(n)
+	case int:
+		return n
+	}
+	return 0
+}
+
+// ModelExists reports whether the model is available on the Ollama server
+func (p *Provider) ModelExists(ctx context.Context) (bool, error) {
+	_, err := p.client.Show(ctx, &api.ShowRequest{Model: p.model})
+	if err == nil {
+		return true, nil
+	}
+	var statusErr api.StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		return false, nil
 	}
-	return strings.Contains(resp.Modelfile, "<tools>")
+	return false, err
+}
+
+// Pull downloads the model, reporting progress to fn
+func (p *Provider) Pull(ctx context.Context, fn api.PullProgressFunc) error {
+	return p.client.Pull(ctx, &api.PullRequest{Model: p.model}, fn)
+}
+
+// Preload loads the model into memory so that the first request is not
+// delayed. It sends a chat request without messages, which Ollama treats as a
+// load request.
+func (p *Provider) Preload(ctx context.Context) error {
+	return p.client.Chat(ctx, &api.ChatRequest{
+		Model:     p.model,
+		Messages:  []api.Message{},
+		Stream:    boolPtr(false),
+		KeepAlive: p.keepAlive,
+		Options:   p.options,
+	}, func(api.ChatResponse) error {
+		return nil
+	})
 }
 
 func (p *Provider) Name() string {
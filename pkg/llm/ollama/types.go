@@ -13,6 +13,7 @@ import (
 type OllamaMessage struct {
 	Message    api.Message
 	ToolCallID string // Store tool call ID separately since Ollama API doesn't have this field
+	DoneReason string
 }
 
 func (m *OllamaMessage) GetRole() string {
@@ -36,6 +37,19 @@ func (m *OllamaMessage) GetUsage() (int, int) {
 	return 0, 0 // Ollama doesn't provide token usage info
 }
 
+func (m *OllamaMessage) GetStopReason() llm.StopReason {
+	switch m.DoneReason {
+	case "length":
+		return llm.StopReasonMaxTokens
+	case "stop":
+		if len(m.Message.ToolCalls) > 0 {
+			return llm.StopReasonToolUse
+		}
+		return llm.StopReasonEnd
+	}
+	return llm.StopReasonUnknown
+}
+
 func (m *OllamaMessage) IsToolResponse() bool {
 	return m.Message.Role == "tool"
 }
@@ -14,14 +14,18 @@ type Client struct {
 	client  *http.Client
 }
 
-func NewClient(apiKey string, baseURL string) *Client {
+// NewClient creates an API client. A nil httpClient uses http.DefaultClient.
+func NewClient(apiKey string, baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
 	if baseURL == "" {
 		baseURL = "https://api.openai.com/v1"
 	}
 	return &Client{
 		apiKey:  apiKey,
 		baseURL: baseURL,
-		client:  &http.Client{},
+		client:  httpClient,
 	}
 }
 
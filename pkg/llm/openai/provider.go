@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/charmbracelet/log"
@@ -31,9 +32,9 @@ func convertSchema(schema llm.Schema) map[string]interface{} {
 	}
 }
 
-func NewProvider(apiKey, baseURL, model, systemPrompt string) *Provider {
+func NewProvider(apiKey, baseURL, model, systemPrompt string, httpClient *http.Client) *Provider {
 	return &Provider{
-		client:       NewClient(apiKey, baseURL),
+		client:       NewClient(apiKey, baseURL, httpClient),
 		model:        model,
 		systemPrompt: systemPrompt,
 	}
@@ -44,7 +45,9 @@ func (p *Provider) CreateMessage(
 	prompt string,
 	messages []llm.Message,
 	tools []llm.Tool,
+	opts ...llm.RequestOption,
 ) (llm.Message, error) {
+	options := llm.NewRequestOptions(opts...)
 	log.Debug("creating message",
 		"prompt", prompt,
 		"num_messages", len(messages),
@@ -176,13 +179,38 @@ func (p *Provider) CreateMessage(
 		}
 	}
 
+	var responseFormat *ResponseFormat
+	if options.OutputSchema != nil {
+		// Strict mode rejects many ordinary schemas, the answer is validated locally instead
+		responseFormat = &ResponseFormat{
+			Type: "json_schema",
+			JSONSchema: &JSONSchema{
+				Name:   "output",
+				Schema: options.OutputSchema,
+			},
+		}
+	}
+
+	// tool_choice is rejected when no tools are sent
+	var toolChoice interface{}
+	if options.ToolChoice != nil && len(tools) > 0 {
+		toolChoice = convertToolChoice(*options.ToolChoice)
+	}
+
+	maxTokens := llm.DefaultMaxTokens
+	if options.MaxTokens > 0 {
+		maxTokens = options.MaxTokens
+	}
+
 	// Make the API call
 	resp, err := p.client.CreateChatCompletion(ctx, CreateRequest{
-		Model:       p.model,
-		Messages:    openaiMessages,
-		Tools:       openaiTools,
-		MaxTokens:   4096,
-		Temperature: 0.7,
+		Model:          p.model,
+		Messages:       openaiMessages,
+		Tools:          openaiTools,
+		MaxTokens:      maxTokens,
+		Temperature:    0.7,
+		ResponseFormat: responseFormat,
+		ToolChoice:     toolChoice,
 	})
 	if err != nil {
 		return nil, err
@@ -195,8 +223,30 @@ func (p *Provider) CreateMessage(
 	return &Message{Resp: resp, Choice: &resp.Choices[0]}, nil
 }
 
-func (p *Provider) SupportsTools() bool {
-	return true
+func convertToolChoice(choice llm.ToolChoice) interface{} {
+	switch choice.Mode {
+	case llm.ToolChoiceNone:
+		return "none"
+	case llm.ToolChoiceRequired:
+		return "required"
+	case llm.ToolChoiceTool:
+		return map[string]interface{}{
+			"type":     "function",
+			"function": map[string]string{"name": choice.Name},
+		}
+	default:
+		return "auto"
+	}
+}
+
+func (p *Provider) Capabilities() llm.Capabilities {
+	caps := llm.ModelCapabilities("openai", p.model)
+	caps.Tools = true
+	caps.ParallelToolCalls = true
+	caps.SystemPrompt = true
+	caps.DefaultOutputTokens = llm.DefaultMaxTokens
+	caps.JSONSchemaOutput = true
+	return caps
 }
 
 func (p *Provider) Name() string {
@@ -328,6 +378,18 @@ func (m *Message) GetUsage() (int, int) {
 	return m.Resp.Usage.PromptTokens, m.Resp.Usage.CompletionTokens
 }
 
+func (m *Message) GetStopReason() llm.StopReason {
+	switch m.Choice.FinishReason {
+	case "length":
+		return llm.StopReasonMaxTokens
+	case "tool_calls", "function_call":
+		return llm.StopReasonToolUse
+	case "stop":
+		return llm.StopReasonEnd
+	}
+	return llm.StopReasonUnknown
+}
+
 // ToolCallWrapper implements llm.ToolCall
 type ToolCallWrapper struct {
 	Call ToolCall
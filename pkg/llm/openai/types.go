@@ -1,11 +1,24 @@
 package openai
 
 type CreateRequest struct {
-	Model       string         `json:"model"`
-	Messages    []MessageParam `json:"messages"`
-	Tools       []Tool         `json:"tools,omitempty"`
-	MaxTokens   int            `json:"max_tokens,omitempty"`
-	Temperature float32        `json:"temperature,omitempty"`
+	Model          string          `json:"model"`
+	Messages       []MessageParam  `json:"messages"`
+	Tools          []Tool          `json:"tools,omitempty"`
+	MaxTokens      int             `json:"max_tokens,omitempty"`
+	Temperature    float32         `json:"temperature,omitempty"`
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	ToolChoice     interface{}     `json:"tool_choice,omitempty"`
+}
+
+type ResponseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"`
+}
+
+type JSONSchema struct {
+	Name   string                 `json:"name"`
+	Schema map[string]interface{} `json:"schema"`
+	Strict bool                   `json:"strict"`
 }
 
 type MessageParam struct {
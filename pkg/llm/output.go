@@ -0,0 +1,84 @@
+package llm
+
+import "slices"
+
+// OutputToolName is the name of the synthetic tool used by providers that
+// implement structured output through a forced tool call
+const OutputToolName = "structured_output"
+
+// outputWrapperKey holds the answer when the schema's root is not an object,
+// since tool inputs must always be objects
+const outputWrapperKey = "result"
+
+// definitionKeys hold the subschemas that $ref points to, which have to stay
+// at the root of the tool's input schema
+var definitionKeys = []string{"$defs", "definitions"}
+
+// OutputTool returns a tool definition whose input is the structured answer.
+// Object schemas are passed through whole, others are wrapped in an object.
+func OutputTool(schema map[string]interface{}) Tool {
+	tool := Tool{
+		Name:        OutputToolName,
+		Description: "Respond to the user with the final answer. The input must match the required output schema.",
+	}
+
+	if schema["type"] == "object" {
+		properties, _ := schema["properties"].(map[string]interface{})
+		tool.InputSchema = Schema{
+			Type:       "object",
+			Properties: properties,
+			Required:   stringSlice(schema["required"]),
+			Extra:      make(map[string]interface{}),
+		}
+		for key, value := range schema {
+			switch key {
+			case "type", "properties", "required", "$schema":
+			default:
+				tool.InputSchema.Extra[key] = value
+			}
+		}
+		return tool
+	}
+
+	wrapped := make(map[string]interface{}, len(schema))
+	extra := make(map[string]interface{})
+	for key, value := range schema {
+		if slices.Contains(definitionKeys, key) {
+			extra[key] = value
+		} else if key != "$schema" {
+			wrapped[key] = value
+		}
+	}
+	tool.InputSchema = Schema{
+		Type:       "object",
+		Properties: map[string]interface{}{outputWrapperKey: wrapped},
+		Required:   []string{outputWrapperKey},
+		Extra:      extra,
+	}
+	return tool
+}
+
+// OutputFromToolArguments extracts the structured answer from the arguments
+// of a call to the tool returned by OutputTool
+func OutputFromToolArguments(schema map[string]interface{}, args map[string]interface{}) interface{} {
+	if schema["type"] == "object" {
+		return args
+	}
+	return args[outputWrapperKey]
+}
+
+func stringSlice(v interface{}) []string {
+	switch s := v.(type) {
+	case []string:
+		return s
+	case []interface{}:
+		result := make([]string, 0, len(s))
+		for _, item := range s {
+			if str, ok := item.(string); ok {
+				result = append(result, str)
+			}
+		}
+		return result
+	}
+	return nil
+}
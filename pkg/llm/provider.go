@@ -1,6 +1,9 @@
 package llm
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+)
 
 // Message represents a message in the conversation
 type Message interface {
@@ -47,19 +50,79 @@ type Schema struct {
 	Type       string                 `json:"type"`
 	Properties map[string]interface{} `json:"properties"`
 	Required   []string               `json:"required"`
+	// Extra holds the schema's other keywords, such as $defs or
+	// additionalProperties
+	Extra map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON writes Extra alongside the other keywords
+func (s Schema) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(s.Extra)+3)
+	for key, value := range s.Extra {
+		m[key] = value
+	}
+	m["type"] = s.Type
+	m["properties"] = s.Properties
+	m["required"] = s.Required
+	return json.Marshal(m)
 }
 
 // Provider defines the interface for LLM providers
 type Provider interface {
 	// CreateMessage sends a message to the LLM and returns the response
-	CreateMessage(ctx context.Context, prompt string, messages []Message, tools []Tool) (Message, error)
+	CreateMessage(ctx context.Context, prompt string, messages []Message, tools []Tool, opts ...RequestOption) (Message, error)
 
 	// CreateToolResponse creates a message representing a tool response
 	CreateToolResponse(toolCallID string, content interface{}) (Message, error)
 
-	// SupportsTools returns whether this provider supports tool/function calling
-	SupportsTools() bool
+	// Capabilities describes what the provider and model support
+	Capabilities() Capabilities
 
 	// Name returns the provider's name
 	Name() string
 }
+
+// RequestOptions holds optional settings for a single CreateMessage call
+type RequestOptions struct {
+	// OutputSchema is a JSON Schema the final answer must conform to
+	OutputSchema map[string]interface{}
+
+	// ToolChoice controls tool use, nil leaves it to the model
+	ToolChoice *ToolChoice
+
+	// MaxTokens overrides the output token limit when non-zero
+	MaxTokens int
+}
+
+// RequestOption configures a single CreateMessage call
+type RequestOption func(*RequestOptions)
+
+// WithOutputSchema asks the model to answer with JSON matching the schema
+func WithOutputSchema(schema map[string]interface{}) RequestOption {
+	return func(o *RequestOptions) {
+		o.OutputSchema = schema
+	}
+}
+
+// WithToolChoice controls whether and which tools the model calls
+func WithToolChoice(choice ToolChoice) RequestOption {
+	return func(o *RequestOptions) {
+		o.ToolChoice = &choice
+	}
+}
+
+// WithMaxTokens sets the output token limit for the request
+func WithMaxTokens(n int) RequestOption {
+	return func(o *RequestOptions) {
+		o.MaxTokens = n
+	}
+}
+
+// NewRequestOptions applies opts to an empty RequestOptions
+func NewRequestOptions(opts ...RequestOption) RequestOptions {
+	var o RequestOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
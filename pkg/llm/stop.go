@@ -0,0 +1,31 @@
+package llm
+
+// DefaultMaxTokens is the output token limit used when a request does not
+// set one and the provider requires it
+const DefaultMaxTokens = 4096
+
+// StopReason says why the model stopped generating
+type StopReason string
+
+const (
+	// StopReasonUnknown is used when the provider does not say
+	StopReasonUnknown StopReason = ""
+	// StopReasonEnd means the model finished its answer
+	StopReasonEnd StopReason = "end"
+	// StopReasonToolUse means the model stopped to call tools
+	StopReasonToolUse StopReason = "tool_use"
+	// StopReasonMaxTokens means the output token limit cut the answer off
+	StopReasonMaxTokens StopReason = "max_tokens"
+)
+
+// StopReasonMessage is implemented by messages that report why generation
+// stopped
+type StopReasonMessage interface {
+	GetStopReason() StopReason
+}
+
+// IsTruncated reports whether the message was cut off at the output token limit
+func IsTruncated(msg Message) bool {
+	m, ok := msg.(StopReasonMessage)
+	return ok && m.GetStopReason() == StopReasonMaxTokens
+}
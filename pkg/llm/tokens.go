@@ -0,0 +1,39 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// charsPerToken is the rough ratio used by the heuristic estimator. It is
+// deliberately a little pessimistic for English prose so that estimates err
+// on the side of keeping the request under the limit.
+const charsPerToken = 3.5
+
+// TokenCounter is implemented by providers that expose an exact
+// count-tokens endpoint
+type TokenCounter interface {
+	// CountTokens returns the number of input tokens the given request would use,
+	// including the system prompt and tool definitions
+	CountTokens(ctx context.Context, messages []Message, tools []Tool) (int, error)
+}
+
+// EstimateTextTokens returns a heuristic token count for a piece of text
+func EstimateTextTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	return int(float64(len(text))/charsPerToken) + 1
+}
+
+// EstimateToolTokens returns a heuristic token count for a set of tool definitions
+func EstimateToolTokens(tools []Tool) int {
+	total := 0
+	for _, tool := range tools {
+		schema, _ := json.Marshal(tool.InputSchema)
+		total += EstimateTextTokens(tool.Name) +
+			EstimateTextTokens(tool.Description) +
+			EstimateTextTokens(string(schema))
+	}
+	return total
+}
@@ -0,0 +1,63 @@
+package llm
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrToolChoiceUnsupported is returned by providers that cannot honor a tool choice
+var ErrToolChoiceUnsupported = errors.New("tool choice not supported by provider")
+
+// ToolChoiceMode selects how the model may use tools
+type ToolChoiceMode string
+
+const (
+	// ToolChoiceAuto lets the model decide whether to call tools
+	ToolChoiceAuto ToolChoiceMode = "auto"
+	// ToolChoiceNone prevents the model from calling tools
+	ToolChoiceNone ToolChoiceMode = "none"
+	// ToolChoiceRequired forces the model to call at least one tool
+	ToolChoiceRequired ToolChoiceMode = "required"
+	// ToolChoiceTool forces the model to call the tool named in ToolChoice.Name
+	ToolChoiceTool ToolChoiceMode = "tool"
+)
+
+// ToolChoice controls whether and which tools the model calls
+type ToolChoice struct {
+	Mode ToolChoiceMode
+	Name string
+}
+
+// ParseToolChoice parses auto, none, required (or any), or a tool name
+func ParseToolChoice(s string) ToolChoice {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "auto":
+		return ToolChoice{Mode: ToolChoiceAuto}
+	case "none":
+		return ToolChoice{Mode: ToolChoiceNone}
+	case "required", "any":
+		return ToolChoice{Mode: ToolChoiceRequired}
+	}
+	return ToolChoice{Mode: ToolChoiceTool, Name: strings.TrimSpace(s)}
+}
+
+func (c ToolChoice) String() string {
+	if c.Mode == ToolChoiceTool {
+		return c.Name
+	}
+	if c.Mode == "" {
+		return string(ToolChoiceAuto)
+	}
+	return string(c.Mode)
+}
+
+// IsAuto reports whether the choice leaves tool use up to the model
+func (c ToolChoice) IsAuto() bool {
+	return c.Mode == "" || c.Mode == ToolChoiceAuto
+}
+
+// ToolChoiceValidator is implemented by providers that can only honor some
+// tool choices, so that unsupported ones can be rejected up front
+type ToolChoiceValidator interface {
+	ValidateToolChoice(choice ToolChoice) error
+}